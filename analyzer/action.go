@@ -3,67 +3,68 @@ package analyzer
 import (
 	"context"
 	"fmt"
+	"path"
+	"sort"
 	"strconv"
-	"strings"
+	"time"
 
 	"github.com/google/go-github/v62/github"
 )
 
-// GetIntegrationIssues returns the number of integration issues in the period.
+// GetIntegrationIssues returns the number of issues with any of the integration labels in the period.
 func (a *Analyzer) GetIntegrationIssues(ctx context.Context, repo string) (int, error) {
-	opts := &github.IssueListByRepoOptions{Labels: []string{"bug-integration"}, Since: a.StartDate, State: "all", ListOptions: github.ListOptions{PerPage: 100}}
-	count := 0
-	for {
-		issues, resp, err := a.client.Issues.ListByRepo(ctx, a.Owner, repo, opts)
-		if err != nil {
-			return 0, err
-		}
-		for _, i := range issues {
-			if i.CreatedAt.Before(a.EndDate) {
-				count++
-			}
-		}
-		if resp.NextPage == 0 {
-			break
-		}
-		opts.Page = resp.NextPage
-		a.checkRateLimit(resp)
+	issues, err := a.listIssues(ctx, repo, a.IntegrationLabels)
+	if err != nil {
+		return 0, err
 	}
-	return count, nil
+	return len(issues), nil
 }
 
 // GetRevertRate returns the rate of revert commits in the period.
+// A commit is a revert when its subject has the `Revert "..."` prefix generated by git and GitHub, or its
+// body has git's "This reverts commit <sha>." line. When DetectRevertPRs is set, merged PRs whose title
+// starts with "Revert" are counted as well, unless their merge commit was already counted.
 func (a *Analyzer) GetRevertRate(ctx context.Context, repo string) (float64, error) {
-	opts := &github.CommitsListOptions{
-		Since:       a.StartDate,
-		Until:       a.EndDate,
-		ListOptions: github.ListOptions{PerPage: 100},
+	commits, err := a.getCommits(ctx, repo)
+	if err != nil {
+		return 0, err
 	}
 
-	totalCommits := 0
+	totalCommits := len(commits)
 	reverts := 0
+	revertSHAs := make(map[string]struct{})
 
-	for {
-		commits, resp, err := a.client.Repositories.ListCommits(ctx, a.Owner, repo, opts)
+	for _, c := range commits {
+		if c.Commit != nil && c.Commit.Message != nil {
+			if isRevertMessage(*c.Commit.Message) {
+				reverts++
+				revertSHAs[c.GetSHA()] = struct{}{}
+			}
+		}
+	}
+
+	if a.DetectRevertPRs {
+		prs, err := a.listPullRequests(ctx, repo, "closed")
 		if err != nil {
 			return 0, err
 		}
-
-		totalCommits += len(commits)
-
-		for _, c := range commits {
-			if c.Commit != nil && c.Commit.Message != nil {
-				if strings.Contains(strings.ToLower(*c.Commit.Message), "revert") {
-					reverts++
+		for _, pr := range prs {
+			if pr.MergedAt == nil || !revertPRTitle.MatchString(pr.GetTitle()) {
+				continue
+			}
+			sha := pr.GetMergeCommitSHA()
+			if sha == "" {
+				// PRs from search results don't carry the merge commit
+				full, err := a.getPullRequest(ctx, repo, pr.GetNumber())
+				if err != nil {
+					return 0, err
 				}
+				sha = full.GetMergeCommitSHA()
+			}
+			if _, ok := revertSHAs[sha]; !ok {
+				reverts++
 			}
 		}
-
-		if resp.NextPage == 0 {
-			break
-		}
-		opts.Page = resp.NextPage
-		a.checkRateLimit(resp)
 	}
 
 	if totalCommits == 0 {
@@ -75,54 +76,395 @@ func (a *Analyzer) GetRevertRate(ctx context.Context, repo string) (float64, err
 
 // GetWorkflowFailures returns the count of workflow failures in the period.
 func (a *Analyzer) GetWorkflowFailures(ctx context.Context, repo string) (int, error) {
-	workflowIDInt, err := strconv.ParseInt(a.WorkflowID, 10, 64)
+	runs, err := a.listWorkflowRuns(ctx, repo)
 	if err != nil {
-		return 0, fmt.Errorf("invalid workflow ID: %v", err)
+		return 0, err
 	}
-	opts := &github.ListWorkflowRunsOptions{Created: fmt.Sprintf("%s..%s", a.StartDate.Format("2006-01-02"), a.EndDate.Format("2006-01-02")), ListOptions: github.ListOptions{PerPage: 100}}
-	count := 0
-	for {
-		runs, resp, err := a.client.Actions.ListWorkflowRunsByID(ctx, a.Owner, repo, workflowIDInt, opts)
+	return countFailures(runs), nil
+}
+
+// GetSuccessfulDeploys returns the count of successful deploys (runs with success and attempt==1).
+func (a *Analyzer) GetSuccessfulDeploys(ctx context.Context, repo string) (int, error) {
+	runs, err := a.listWorkflowRuns(ctx, repo)
+	if err != nil {
+		return 0, err
+	}
+	return countDeploys(runs), nil
+}
+
+// GetWorkflowFailuresByName returns the count of workflow failures in the period for each workflow
+// of Workflows (just WorkflowID when empty), keyed as configured.
+func (a *Analyzer) GetWorkflowFailuresByName(ctx context.Context, repo string) (map[string]int, error) {
+	return a.countByWorkflow(ctx, repo, countFailures)
+}
+
+// GetSuccessfulDeploysByName returns the count of successful deploys in the period (see GetSuccessfulDeploys)
+// for each workflow of Workflows (just WorkflowID when empty), keyed as configured.
+func (a *Analyzer) GetSuccessfulDeploysByName(ctx context.Context, repo string) (map[string]int, error) {
+	return a.countByWorkflow(ctx, repo, countDeploys)
+}
+
+// countByWorkflow applies count to the runs of each workflow of a.workflows().
+func (a *Analyzer) countByWorkflow(ctx context.Context, repo string, count func([]*github.WorkflowRun) int) (map[string]int, error) {
+	counts := make(map[string]int)
+	for _, workflow := range a.workflows() {
+		runs, err := a.listWorkflowRunsOf(ctx, repo, workflow)
 		if err != nil {
-			return 0, err
+			return nil, err
 		}
-		for _, run := range runs.WorkflowRuns {
-			if *run.Conclusion == "failure" {
-				count++
-			}
+		counts[workflow] = count(runs)
+	}
+	return counts, nil
+}
+
+// workflows returns the workflows broken down by the *ByName metrics: Workflows, or WorkflowID when empty.
+func (a *Analyzer) workflows() []string {
+	if len(a.Workflows) > 0 {
+		return a.Workflows
+	}
+	return []string{a.WorkflowID}
+}
+
+// configuredWorkflows returns WorkflowID followed by the other workflows of Workflows,
+// i.e. every workflow a run queries.
+func (a *Analyzer) configuredWorkflows() []string {
+	workflows := []string{a.WorkflowID}
+	for _, w := range a.Workflows {
+		if w != a.WorkflowID {
+			workflows = append(workflows, w)
 		}
-		if resp.NextPage == 0 {
-			break
+	}
+	return workflows
+}
+
+// countFailures returns the number of failed runs.
+func countFailures(runs []*github.WorkflowRun) int {
+	count := 0
+	for _, run := range runs {
+		if run.GetConclusion() == "failure" {
+			count++
 		}
-		opts.Page = resp.NextPage
-		a.checkRateLimit(resp)
 	}
-	return count, nil
+	return count
 }
 
-// GetSuccessfulDeploys returns the count of successful deploys (runs with success and attempt==1).
-func (a *Analyzer) GetSuccessfulDeploys(ctx context.Context, repo string) (int, error) {
-	workflowIDInt, err := strconv.ParseInt(a.WorkflowID, 10, 64)
+// countDeploys returns the number of runs that succeeded on their first attempt.
+func countDeploys(runs []*github.WorkflowRun) int {
+	count := 0
+	for _, run := range runs {
+		if run.GetConclusion() == "success" && run.GetRunAttempt() == 1 {
+			count++
+		}
+	}
+	return count
+}
+
+// GetDeploymentFrequency returns the number of deployments per day in the period (DORA deployment frequency).
+// Every successful run of the deploy workflow counts as a deployment, including successful re-runs.
+// The period length is taken in whole days and must be at least one day.
+func (a *Analyzer) GetDeploymentFrequency(ctx context.Context, repo string) (float64, error) {
+	days := int(a.EndDate.Sub(a.StartDate).Hours() / 24)
+	if days <= 0 {
+		return 0, fmt.Errorf("invalid period: %s to %s is shorter than one day", a.StartDate.Format("2006-01-02"), a.EndDate.Format("2006-01-02"))
+	}
+
+	runs, err := a.listWorkflowRuns(ctx, repo)
 	if err != nil {
-		return 0, fmt.Errorf("invalid workflow ID: %v", err)
+		return 0, err
+	}
+	deploys := 0
+	for _, run := range runs {
+		if run.GetConclusion() == "success" {
+			deploys++
+		}
 	}
-	opts := &github.ListWorkflowRunsOptions{Created: fmt.Sprintf("%s..%s", a.StartDate.Format("2006-01-02"), a.EndDate.Format("2006-01-02")), ListOptions: github.ListOptions{PerPage: 100}}
+	return float64(deploys) / float64(days), nil
+}
+
+// GetLeadTimeForChanges returns the average time between a change being authored and deployed (DORA lead time).
+// For each successful deploy run, the commits shipped since the previous successful deploy are compared and
+// the earliest author date is used as the start of the change; the first deploy in the period falls back to
+// its head commit. Re-deploys of an already deployed SHA and runs whose head SHA can't be resolved are skipped.
+// Force-pushes and rewritten history change author dates and comparison bases, so they may skew the result.
+func (a *Analyzer) GetLeadTimeForChanges(ctx context.Context, repo string) (time.Duration, error) {
+	runs, err := a.listWorkflowRuns(ctx, repo)
+	if err != nil {
+		return 0, err
+	}
+
+	var deploys []*github.WorkflowRun
+	for _, run := range runs {
+		if run.GetConclusion() == "success" && run.GetHeadSHA() != "" {
+			deploys = append(deploys, run)
+		}
+	}
+	sort.Slice(deploys, func(i, j int) bool {
+		return deploys[i].GetCreatedAt().Before(deploys[j].GetCreatedAt().Time)
+	})
+
+	var total time.Duration
 	count := 0
+	prevSHA := ""
+	for _, run := range deploys {
+		sha := run.GetHeadSHA()
+		if sha == prevSHA {
+			continue
+		}
+		authored, ok := a.firstAuthorDate(ctx, repo, prevSHA, sha)
+		prevSHA = sha
+		if !ok {
+			continue
+		}
+		total += run.GetUpdatedAt().Sub(authored)
+		count++
+	}
+
+	if count == 0 {
+		return 0, nil
+	}
+	return total / time.Duration(count), nil
+}
+
+// GetChangeFailureRate returns the percentage of deployments that led to a failure or a rollback (DORA change failure rate).
+// Deployments are the completed runs of the deploy workflow that succeeded or failed. Each rollback issue is
+// attributed to the latest deployment started before it was opened, and a deployment counts as failed at most
+// once, so a bad deploy with both a failed run and a rollback issue isn't counted twice. Rollback issues opened
+// before the first deployment of the period are ignored. Returns 0 when there were no deployments.
+func (a *Analyzer) GetChangeFailureRate(ctx context.Context, repo string) (float64, error) {
+	runs, err := a.listWorkflowRuns(ctx, repo)
+	if err != nil {
+		return 0, err
+	}
+	rollbacks, err := a.listIssues(ctx, repo, a.RollbackLabels)
+	if err != nil {
+		return 0, err
+	}
+
+	var deploys []*github.WorkflowRun
+	for _, run := range runs {
+		if c := run.GetConclusion(); c == "success" || c == "failure" {
+			deploys = append(deploys, run)
+		}
+	}
+	if len(deploys) == 0 {
+		return 0, nil
+	}
+	sort.Slice(deploys, func(i, j int) bool {
+		return deploys[i].GetCreatedAt().Before(deploys[j].GetCreatedAt().Time)
+	})
+
+	failed := make([]bool, len(deploys))
+	for i, run := range deploys {
+		failed[i] = run.GetConclusion() == "failure"
+	}
+	for _, issue := range rollbacks {
+		opened := issue.GetCreatedAt().Time
+		// index of the first deploy started after the issue was opened
+		idx := sort.Search(len(deploys), func(i int) bool {
+			return deploys[i].GetCreatedAt().After(opened)
+		})
+		if idx > 0 {
+			failed[idx-1] = true
+		}
+	}
+
+	failures := 0
+	for _, f := range failed {
+		if f {
+			failures++
+		}
+	}
+	return float64(failures) / float64(len(deploys)) * 100, nil
+}
+
+// GetMTTR returns the mean time to recovery (DORA), measured from the creation to the closing of rollback issues
+// in the period. Rollback issues still open are excluded, unless MTTRIncludeOpen is set, in which case they are
+// considered recovered at EndDate.
+func (a *Analyzer) GetMTTR(ctx context.Context, repo string) (time.Duration, error) {
+	issues, err := a.listIssues(ctx, repo, a.RollbackLabels)
+	if err != nil {
+		return 0, err
+	}
+
+	var total time.Duration
+	count := 0
+	for _, issue := range issues {
+		recovered := a.EndDate
+		if issue.ClosedAt != nil {
+			recovered = issue.ClosedAt.Time
+		} else if !a.MTTRIncludeOpen {
+			continue
+		}
+		total += recovered.Sub(issue.GetCreatedAt().Time)
+		count++
+	}
+
+	if count == 0 {
+		return 0, nil
+	}
+	return total / time.Duration(count), nil
+}
+
+// firstAuthorDate returns the earliest author date of the commits in base..head.
+// When base is empty or the comparison fails, the author date of head itself is used.
+func (a *Analyzer) firstAuthorDate(ctx context.Context, repo, base, head string) (time.Time, bool) {
+	owner, name := a.splitRepo(repo)
+	if base != "" {
+		cmp, _, err := doRequest(ctx, a, func() (*github.CommitsComparison, *github.Response, error) {
+			return a.provider.CompareCommits(ctx, owner, name, base, head, nil)
+		})
+		if err == nil {
+			var first time.Time
+			for _, c := range cmp.Commits {
+				date := c.GetCommit().GetAuthor().GetDate().Time
+				if !date.IsZero() && (first.IsZero() || date.Before(first)) {
+					first = date
+				}
+			}
+			if !first.IsZero() {
+				return first, true
+			}
+		}
+	}
+
+	commit, _, err := doRequest(ctx, a, func() (*github.RepositoryCommit, *github.Response, error) {
+		return a.provider.GetCommit(ctx, owner, name, head, nil)
+	})
+	if err != nil {
+		return time.Time{}, false
+	}
+	date := commit.GetCommit().GetAuthor().GetDate().Time
+	return date, !date.IsZero()
+}
+
+// GetAvgWorkflowDuration returns the average duration of the completed runs of the workflow in the period,
+// measured from the start of the run (RunStartedAt) to its last update. Runs missing either timestamp are skipped.
+func (a *Analyzer) GetAvgWorkflowDuration(ctx context.Context, repo string) (time.Duration, error) {
+	runs, err := a.listWorkflowRuns(ctx, repo)
+	if err != nil {
+		return 0, err
+	}
+
+	var total time.Duration
+	count := 0
+	for _, run := range runs {
+		if run.GetStatus() != "completed" || run.RunStartedAt == nil || run.UpdatedAt == nil {
+			continue
+		}
+		total += run.UpdatedAt.Sub(run.RunStartedAt.Time)
+		count++
+	}
+
+	if count == 0 {
+		return 0, nil
+	}
+	return total / time.Duration(count), nil
+}
+
+// GetWorkflowDurationHistogram returns the number of completed runs of the workflow in the period by
+// duration bucket: "0-2m", "2-5m", "5-10m" and "10m+". Durations are measured like GetAvgWorkflowDuration.
+// Every bucket is present, even when empty.
+func (a *Analyzer) GetWorkflowDurationHistogram(ctx context.Context, repo string) (map[string]int, error) {
+	runs, err := a.listWorkflowRuns(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	histogram := map[string]int{"0-2m": 0, "2-5m": 0, "5-10m": 0, "10m+": 0}
+	for _, run := range runs {
+		if run.GetStatus() != "completed" || run.RunStartedAt == nil || run.UpdatedAt == nil {
+			continue
+		}
+		switch d := run.UpdatedAt.Sub(run.RunStartedAt.Time); {
+		case d < 2*time.Minute:
+			histogram["0-2m"]++
+		case d < 5*time.Minute:
+			histogram["2-5m"]++
+		case d < 10*time.Minute:
+			histogram["5-10m"]++
+		default:
+			histogram["10m+"]++
+		}
+	}
+	return histogram, nil
+}
+
+// listWorkflowRuns returns all runs of the configured workflow (WorkflowID) created in the period.
+// The list is shared by every action metric of a run.
+func (a *Analyzer) listWorkflowRuns(ctx context.Context, repo string) ([]*github.WorkflowRun, error) {
+	return a.listWorkflowRunsOf(ctx, repo, a.WorkflowID)
+}
+
+// listWorkflowRunsOf returns all runs of the given workflow created in the period, to the second,
+// with the bounds expressed in Timezone.
+// The list is fetched once per repo, workflow and period.
+func (a *Analyzer) listWorkflowRunsOf(ctx context.Context, repo, workflow string) ([]*github.WorkflowRun, error) {
+	owner, name := a.splitRepo(repo)
+	return memoize(a.memo, a.memoKey("workflow-runs#"+workflow, repo), func() ([]*github.WorkflowRun, error) {
+		workflowIDInt, err := a.resolveWorkflow(ctx, repo, workflow)
+		if err != nil {
+			return nil, err
+		}
+		// Full timestamps rather than dates, so runs near midnight land in the right period
+		loc := a.timezone()
+		created := fmt.Sprintf("%s..%s", a.StartDate.In(loc).Format(time.RFC3339), a.EndDate.In(loc).Format(time.RFC3339))
+		opts := &github.ListWorkflowRunsOptions{Created: created, ListOptions: github.ListOptions{PerPage: 100}}
+		var allRuns []*github.WorkflowRun
+		for {
+			runs, resp, err := doRequest(ctx, a, func() (*github.WorkflowRuns, *github.Response, error) {
+				return a.provider.ListWorkflowRuns(ctx, owner, name, workflowIDInt, opts)
+			})
+			if err != nil {
+				return nil, err
+			}
+			allRuns = append(allRuns, runs.WorkflowRuns...)
+			if a.lastPage(resp, opts.Page) {
+				break
+			}
+			opts.Page = resp.NextPage
+		}
+		return allRuns, nil
+	})
+}
+
+// resolveWorkflowID returns the numeric ID of the configured workflow (WorkflowID) for a repo.
+func (a *Analyzer) resolveWorkflowID(ctx context.Context, repo string) (int64, error) {
+	return a.resolveWorkflow(ctx, repo, a.WorkflowID)
+}
+
+// resolveWorkflow returns the numeric ID of a workflow for a repo.
+// The workflow may be a numeric ID, a workflow name, or a workflow file
+// (".github/workflows/x.yml" or just "x.yml"). Resolved IDs are cached per repo and workflow; a workflow
+// that isn't found isn't, so a later call looks it up again. Workflow lists are short, so MaxPages doesn't
+// apply: a workflow past the cap would be wrongly reported as ErrWorkflowNotFound.
+func (a *Analyzer) resolveWorkflow(ctx context.Context, repo, workflow string) (int64, error) {
+	owner, name := a.splitRepo(repo)
+	if id, err := strconv.ParseInt(workflow, 10, 64); err == nil {
+		return id, nil
+	}
+	key := repo + "#" + workflow
+	if id, ok := a.workflowIDs.Load(key); ok {
+		return id.(int64), nil
+	}
+
+	opts := &github.ListOptions{PerPage: 100}
 	for {
-		runs, resp, err := a.client.Actions.ListWorkflowRunsByID(ctx, a.Owner, repo, workflowIDInt, opts)
+		workflows, resp, err := doRequest(ctx, a, func() (*github.Workflows, *github.Response, error) {
+			return a.provider.ListWorkflows(ctx, owner, name, opts)
+		})
 		if err != nil {
 			return 0, err
 		}
-		for _, run := range runs.WorkflowRuns {
-			if *run.Conclusion == "success" && *run.RunAttempt == 1 {
-				count++
+		for _, w := range workflows.Workflows {
+			if w.GetName() == workflow || w.GetPath() == workflow || path.Base(w.GetPath()) == workflow {
+				a.workflowIDs.Store(key, w.GetID())
+				return w.GetID(), nil
 			}
 		}
 		if resp.NextPage == 0 {
 			break
 		}
 		opts.Page = resp.NextPage
-		a.checkRateLimit(resp)
 	}
-	return count, nil
+	return 0, fmt.Errorf("%s/%s: %w: %q", owner, name, ErrWorkflowNotFound, workflow)
 }
@@ -0,0 +1,37 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+)
+
+func TestResolveWorkflowPastMaxPages(t *testing.T) {
+	r := &fakeRepo{workflows: []*github.Workflow{
+		{ID: github.Int64(1), Name: github.String("Lint"), Path: github.String(".github/workflows/lint.yml")},
+		{ID: github.Int64(2), Name: github.String("Docs"), Path: github.String(".github/workflows/docs.yml")},
+	}}
+	p := &fakeProvider{perPage: 1, repos: map[string]*fakeRepo{"api": r}}
+	a := newFakeAnalyzer(p, map[string][]string{"core": {"api"}})
+	a.MaxPages = 1 // caps the metric listings, not the workflow lookup
+	ctx := context.Background()
+
+	if _, err := a.resolveWorkflow(ctx, "api", "ci.yml"); !errors.Is(err, ErrWorkflowNotFound) {
+		t.Fatalf("resolveWorkflow before ci.yml exists: error = %v, want ErrWorkflowNotFound", err)
+	}
+
+	// added since: the miss above wasn't cached, and ci.yml sits past MaxPages
+	r.workflows = append(r.workflows, &github.Workflow{ID: github.Int64(3), Name: github.String("CI"), Path: github.String(".github/workflows/ci.yml")})
+	id, err := a.resolveWorkflow(ctx, "api", "ci.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 3 {
+		t.Errorf("resolveWorkflow = %d, want 3", id)
+	}
+	if pages := p.callsTo("ListWorkflows"); pages != 5 {
+		t.Errorf("resolveWorkflow fetched %d pages, want 5 (2 for the miss, 3 to find ci.yml)", pages)
+	}
+}
@@ -0,0 +1,144 @@
+package analyzer
+
+import (
+	"sort"
+	"sync"
+)
+
+// Aggregate rolls up repo metrics into one summary per area, grouped by the Area recorded in each RepoMetrics,
+// so metrics loaded from another run (see MergeMetrics and CheckResumable) keep the areas they were computed with.
+//
+// Skipped repos (archived, empty, unauthorized) are left out entirely. Counters are summed; a metric that failed
+// (listed in Errors) or is disabled in EnabledMetrics adds nothing. AvgMergeTimeDays is a weighted mean using
+// MergedPRs as weight, while AvgConflictRate, AvgRevertRate, AvgReviewersPerPR and AvgCommentsPerItem are the
+// mean of the repo values, over the repos that produced each of them rather than over Repos, so a failed metric
+// isn't averaged in as a zero. UniqueContributors is summed as well, so people working on several repos are
+// counted more than once.
+func (a *Analyzer) Aggregate(metrics []RepoMetrics) map[string]AreaSummary {
+	summaries := make(map[string]AreaSummary)
+	mergeTimeWeighted := make(map[string]float64)
+	counts := make(map[string]*meanCounts) // area -> repos behind each mean
+
+	for _, m := range metrics {
+		if m.Skipped {
+			continue
+		}
+		produced := func(metric string) bool {
+			if _, failed := m.Errors[metric]; failed {
+				return false
+			}
+			enabled, ok := a.EnabledMetrics[metric]
+			return !ok || enabled
+		}
+
+		area := m.Area
+		s := summaries[area]
+		c := counts[area]
+		if c == nil {
+			c = &meanCounts{}
+			counts[area] = c
+		}
+		s.Area = area
+		s.Repos++
+		s.UniqueContributors += m.UniqueContributors
+		if produced("ConflictRateAndCount") {
+			s.AvgConflictRate += m.ConflictRate
+			c.conflictRate++
+		}
+		s.ConflictMergesCount += m.ConflictMergesCount
+		s.MergedPRs += m.MergedPRs
+		if produced("AvgReviewersPerPR") {
+			s.AvgReviewersPerPR += m.AvgReviewersPerPR
+			c.reviewersPerPR++
+		}
+		s.IntegrationIssues += m.IntegrationIssues
+		if produced("RevertRate") {
+			s.AvgRevertRate += m.RevertRate
+			c.revertRate++
+		}
+		s.MainBranchSizeBytes += m.MainBranchSizeBytes
+		s.MainFileCount += m.MainFileCount
+		s.SuccessfulReruns += m.SuccessfulReruns
+		s.RollbackIssues += m.RollbackIssues
+		s.WorkflowFailures += m.WorkflowFailures
+		s.SuccessfulDeploys += m.SuccessfulDeploys
+		if produced("AvgCommentsPerItem") {
+			s.AvgCommentsPerItem += m.AvgCommentsPerItem
+			c.commentsPerItem++
+		}
+		summaries[area] = s
+
+		mergeTimeWeighted[area] += m.AvgMergeTimeDays * float64(m.MergedPRs)
+	}
+
+	for area, s := range summaries {
+		c := counts[area]
+		s.AvgConflictRate = mean(s.AvgConflictRate, c.conflictRate)
+		s.AvgReviewersPerPR = mean(s.AvgReviewersPerPR, c.reviewersPerPR)
+		s.AvgRevertRate = mean(s.AvgRevertRate, c.revertRate)
+		s.AvgCommentsPerItem = mean(s.AvgCommentsPerItem, c.commentsPerItem)
+		if s.MergedPRs > 0 {
+			s.AvgMergeTimeDays = mergeTimeWeighted[area] / float64(s.MergedPRs)
+		}
+		summaries[area] = s
+	}
+
+	return summaries
+}
+
+// meanCounts is the number of repos of an area behind each mean of its AreaSummary.
+type meanCounts struct {
+	conflictRate, reviewersPerPR, revertRate, commentsPerItem int
+}
+
+// mean returns sum/n, 0 when n is 0.
+func mean(sum float64, n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
+
+// UncategorizedArea is the area of repos not listed in Projects, see AreaForRepo.
+const UncategorizedArea = "Uncategorized"
+
+// areaIndex maps each repo of Projects to its area. It's built on first use and dropped at the start of
+// every run, so a run sees the Projects it was started with (e.g. after -discover replaced them).
+type areaIndex struct {
+	mu    sync.Mutex
+	areas map[string]string // nil until built
+}
+
+func (idx *areaIndex) reset() {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.areas = nil
+}
+
+// AreaForRepo returns the area/product a repo (as listed in Projects) belongs to, or UncategorizedArea when
+// it isn't listed. It's the area recorded in RepoMetrics.Area, so every export and Aggregate agree on it.
+// A repo listed in several areas belongs to the first one in alphabetical order.
+func (a *Analyzer) AreaForRepo(repo string) string {
+	idx := a.areaIndex
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.areas == nil {
+		names := make([]string, 0, len(a.Projects))
+		for area := range a.Projects {
+			names = append(names, area)
+		}
+		sort.Strings(names)
+		idx.areas = make(map[string]string)
+		for _, area := range names {
+			for _, r := range a.Projects[area] {
+				if _, ok := idx.areas[r]; !ok {
+					idx.areas[r] = area
+				}
+			}
+		}
+	}
+	if area, ok := idx.areas[repo]; ok {
+		return area
+	}
+	return UncategorizedArea
+}
@@ -0,0 +1,53 @@
+package analyzer
+
+import "testing"
+
+func TestAggregateLeavesOutSkippedReposAndFailedMetrics(t *testing.T) {
+	metrics := []RepoMetrics{
+		{Area: "core", Repo: "api", ConflictRate: 10, RevertRate: 4, AvgReviewersPerPR: 2, AvgCommentsPerItem: 3, MergedPRs: 4, AvgMergeTimeDays: 1},
+		{Area: "core", Repo: "web", ConflictRate: 30, AvgReviewersPerPR: 1, MergedPRs: 1, AvgMergeTimeDays: 6,
+			Errors: map[string]string{"RevertRate": "timeout", "AvgCommentsPerItem": "timeout"}},
+		{Area: "core", Repo: "old", Skipped: true, SkipReason: "archived"},
+		{Area: "payments", Repo: "ledger", Errors: map[string]string{"ConflictRateAndCount": "timeout", "RevertRate": "timeout",
+			"AvgReviewersPerPR": "timeout", "AvgCommentsPerItem": "timeout"}},
+	}
+
+	tests := []struct {
+		name    string
+		enabled map[string]bool
+		want    map[string]AreaSummary
+	}{
+		{
+			name: "all metrics enabled",
+			want: map[string]AreaSummary{
+				"core": {Area: "core", Repos: 2, AvgConflictRate: 20, AvgRevertRate: 4, AvgReviewersPerPR: 1.5, AvgCommentsPerItem: 3,
+					MergedPRs: 5, AvgMergeTimeDays: 2},
+				"payments": {Area: "payments", Repos: 1},
+			},
+		},
+		{
+			name:    "disabled metric",
+			enabled: map[string]bool{"AvgReviewersPerPR": false},
+			want: map[string]AreaSummary{
+				"core": {Area: "core", Repos: 2, AvgConflictRate: 20, AvgRevertRate: 4, AvgCommentsPerItem: 3,
+					MergedPRs: 5, AvgMergeTimeDays: 2},
+				"payments": {Area: "payments", Repos: 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newFakeAnalyzer(&fakeProvider{}, nil)
+			a.EnabledMetrics = tt.enabled
+			got := a.Aggregate(metrics)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Aggregate returned areas %v, want %v", got, tt.want)
+			}
+			for area, want := range tt.want {
+				if got[area] != want {
+					t.Errorf("Aggregate[%q] = %+v, want %+v", area, got[area], want)
+				}
+			}
+		})
+	}
+}
@@ -2,142 +2,593 @@ package analyzer
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/bradleyfalzon/ghinstallation/v2"
 	"github.com/google/go-github/v62/github"
 	"golang.org/x/oauth2"
 )
 
 // NewAnalyzer creates a new Analyzer instance with an authenticated GitHub client.
+// RollbackLabels defaults to ["rollback"], IntegrationLabels to ["bug-integration"] and
+// Cache to an in-memory cache with a 1 hour TTL, RepoConcurrency to 4, InnerConcurrency to 10,
+// StaleThreshold to 30 days, CountDrafts to true, BotLogins to common bots not suffixed with "[bot]",
+// GlobalConcurrency to 20, BusFactorThreshold to 0.5, BaseBranchFilter to defaultBranch and
+// Logger to the standard logger.
 func NewAnalyzer(owner, defaultBranch, workflowID string, startDate, endDate time.Time, token string, projects map[string][]string) *Analyzer {
 	ctx := context.Background()
 	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
 	tc := oauth2.NewClient(ctx, ts)
 	client := github.NewClient(tc)
 
+	a := NewAnalyzerWithClient(client, owner, defaultBranch, workflowID, startDate, endDate, projects)
+	a.Token = token
+	a.repoScoped = strings.HasPrefix(token, "github_pat_")
+	return a
+}
+
+// NewAnalyzerWithApp creates a new Analyzer authenticated as a GitHub App installation, which has higher
+// rate limits than a personal access token. Installation tokens are minted and refreshed automatically.
+// The App needs read-only access to: actions, contents, pull requests, issues (and metadata).
+func NewAnalyzerWithApp(appID, installationID int64, privateKeyPEM []byte, owner, defaultBranch, workflowID string, startDate, endDate time.Time, projects map[string][]string) (*Analyzer, error) {
+	tr, err := ghinstallation.New(http.DefaultTransport, appID, installationID, privateKeyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GitHub App credentials: %w", err)
+	}
+	client := github.NewClient(&http.Client{Transport: tr})
+
+	a := NewAnalyzerWithClient(client, owner, defaultBranch, workflowID, startDate, endDate, projects)
+	a.repoScoped = true
+	return a, nil
+}
+
+// NewAnalyzerWithClient creates a new Analyzer instance using the given GitHub client.
+// It lets callers bring their own authentication or point the client at a test server
+// (e.g. an httptest.Server via client.WithEnterpriseURLs). Defaults are the same as NewAnalyzer.
+func NewAnalyzerWithClient(client *github.Client, owner, defaultBranch, workflowID string, startDate, endDate time.Time, projects map[string][]string) *Analyzer {
+	a := NewAnalyzerWithProvider(NewGitHubProvider(client), owner, defaultBranch, workflowID, startDate, endDate, projects)
+	a.gql = newGraphQLClient(client)
+	return a
+}
+
+// NewAnalyzerWithProvider creates a new Analyzer instance reading its data from the given Provider
+// (e.g. a fake in tests, or another forge). Defaults are the same as NewAnalyzer; UseGraphQL has no
+// effect, since GraphQL needs a GitHub client.
+func NewAnalyzerWithProvider(provider Provider, owner, defaultBranch, workflowID string, startDate, endDate time.Time, projects map[string][]string) *Analyzer {
 	return &Analyzer{
-		Owner:         owner,
-		DefaultBranch: defaultBranch,
-		WorkflowID:    workflowID,
-		StartDate:     startDate,
-		EndDate:       endDate,
-		Token:         token,
-		Projects:      projects,
-		client:        client,
+		Owner:              owner,
+		DefaultBranch:      defaultBranch,
+		WorkflowID:         workflowID,
+		BaseBranchFilter:   defaultBranch,
+		StartDate:          startDate,
+		EndDate:            endDate,
+		Projects:           projects,
+		RollbackLabels:     []string{"rollback"},
+		IntegrationLabels:  []string{"bug-integration"},
+		Cache:              NewMemoryCache(time.Hour),
+		RepoConcurrency:    4,
+		InnerConcurrency:   10,
+		GlobalConcurrency:  20,
+		StaleThreshold:     30 * 24 * time.Hour,
+		CountDrafts:        true,
+		BotLogins:          []string{"dependabot", "renovate", "github-actions", "renovate-bot", "dependabot-preview"},
+		Logger:             NewStdLogger(nil),
+		BusFactorThreshold: 0.5,
+		provider:           provider,
+		workflowIDs:        &sync.Map{},
+		limiter:            &requestLimiter{},
+		areaIndex:          &areaIndex{},
+		memo:               newMemo(),
+		rateStats:          &rateTracker{},
+		requests:           &atomic.Int64{},
 	}
 }
 
-// Check computes all metrics for all repos sequentially, but metrics per repo in parallel.
+// Check computes all metrics for all repos, processing up to RepoConcurrency repos at a time
+// with the metrics of each repo computed in parallel. The result is sorted by area, then repo name.
+// It's CheckRepos over every repo of Projects.
+//
+// Metrics can be skipped with EnabledMetrics, keyed by the name of their Get method without "Get".
+// From the most to the least expensive, in requests per repo:
+//   - one per commit: ChurnByFile, LineChurnByFile and CodeVolume (shared), DirectPushCount
+//   - one per PR: ConflictRateAndCount (up to 4 per open PR), SelfMerges and MergeMethodDistribution
+//     (shared), ResolvedConflictCount (and the ExcludeDrafts merge time, sharing the timeline),
+//     AvgReviewersPerPR, ReviewOutcomeRates, UnreviewedMerges, AvgTimeToFirstReview, ReviewerLoad and
+//     ApprovedNotMergedCount (sharing the reviews), ReviewerLatency (sharing the reviews and the timeline),
+//     AvgCommentsPerItem (per PR and issue)
+//   - one per deploy: LeadTimeForChanges
+//   - a few listings, shared by the rest: commits, PRs, issues, workflow runs, the git tree for MainSize
 func (a *Analyzer) Check(ctx context.Context) ([]RepoMetrics, error) {
+	return a.CheckRepos(ctx, a.allRepos())
+}
+
+// CheckRepos computes all metrics like Check, but only for the given repos (e.g. to re-run a single failing one).
+// Areas are still looked up in Projects; repos not found in any area get UncategorizedArea.
+// It waits for every repo to finish.
+func (a *Analyzer) CheckRepos(ctx context.Context, repos []string) ([]RepoMetrics, error) {
 	var metrics []RepoMetrics
 
-	// Flatten all repos from projects
+	results, errs := a.stream(ctx, repos)
+	for m := range results {
+		metrics = append(metrics, m)
+	}
+
+	return sortMetrics(metrics), <-errs
+}
+
+// CheckStream computes the metrics of all repos like Check, but emits each RepoMetrics as soon as
+// its repo is done. Both channels are closed once all repos are processed; if ctx is canceled first,
+// the remaining repos are skipped and ctx.Err() is sent on the error channel.
+func (a *Analyzer) CheckStream(ctx context.Context) (<-chan RepoMetrics, <-chan error) {
+	return a.stream(ctx, a.allRepos())
+}
+
+// getRepository returns the repository metadata, shared by the checks of a run.
+// GitHub answers 404 both for a missing repo and for one the token can't see. With a token granted per repo
+// (fine-grained PAT or GitHub App) the latter is far more likely, so it's reported as ErrUnauthorizedRepo;
+// otherwise as ErrRepoNotFound.
+func (a *Analyzer) getRepository(ctx context.Context, repo string) (*github.Repository, error) {
+	owner, name := a.splitRepo(repo)
+	return memoize(a.memo, a.memoKey("repository", repo), func() (*github.Repository, error) {
+		info, _, err := doRequest(ctx, a, func() (*github.Repository, *github.Response, error) {
+			return a.provider.GetRepository(ctx, owner, name)
+		})
+		if err != nil {
+			var errResp *github.ErrorResponse
+			if errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusNotFound {
+				if a.repoScoped {
+					return nil, fmt.Errorf("%s/%s: %w (grant the token access to it): %w", owner, name, ErrUnauthorizedRepo, err)
+				}
+				return nil, fmt.Errorf("%s/%s: %w: %w", owner, name, ErrRepoNotFound, err)
+			}
+			return nil, err
+		}
+		return info, nil
+	})
+}
+
+// allRepos flattens the repos of all areas of Projects.
+func (a *Analyzer) allRepos() []string {
 	var allRepos []string
 	for _, repos := range a.Projects {
 		allRepos = append(allRepos, repos...)
 	}
+	return allRepos
+}
+
+// stream computes the metrics of the given repos with the worker pool of CheckStream.
+func (a *Analyzer) stream(ctx context.Context, allRepos []string) (<-chan RepoMetrics, <-chan error) {
+	results := make(chan RepoMetrics)
+	errs := make(chan error, 1)
+
+	if err := a.ValidatePeriod(); err != nil {
+		close(results)
+		errs <- err
+		close(errs)
+		return results, errs
+	}
 
-	for _, repo := range allRepos {
-		m := RepoMetrics{Repo: repo}
+	// start from fresh data and stats on every run
+	a.memo.reset()
+	a.areaIndex.reset()
+	a.rateStats.reset()
+	a.requests.Store(0)
+
+	workers := a.RepoConcurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	go func() {
+		defer close(results)
+		defer close(errs)
 
 		var wg sync.WaitGroup
+		var mu sync.Mutex
+		done := 0
+		jobs := make(chan string)
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for repo := range jobs {
+					start := time.Now()
+					m := a.checkRepo(ctx, repo)
+					if m.Skipped {
+						a.logEvent(slog.LevelInfo, "repo skipped", "repo", repo, "reason", m.SkipReason)
+					} else {
+						a.logEvent(slog.LevelInfo, "repo done", "repo", repo, "duration", time.Since(start))
+					}
+					mu.Lock()
+					done++
+					a.warnRateLimitBudget(done, len(allRepos)-done)
+					if a.ProgressFunc != nil {
+						a.ProgressFunc(done, len(allRepos), repo)
+					}
+					mu.Unlock()
+					select {
+					case results <- m:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+		}
 
-		// Launch goroutines for each metric
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			m.UniqueContributors, m.ContributorsList, _ = a.GetUniqueContributors(ctx, repo)
-		}()
+	feed:
+		for _, repo := range allRepos {
+			select {
+			case jobs <- repo:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+		close(jobs)
+		wg.Wait()
 
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			m.CommitDist, _ = a.GetCommitDistribution(ctx, repo)
-		}()
+		if err := ctx.Err(); err != nil {
+			errs <- err
+		}
+	}()
 
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			m.ConflictRate, m.ConflictMergesCount, _ = a.GetConflictRateAndCount(ctx, repo)
-		}()
+	return results, errs
+}
 
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			m.AvgMergeTimeDays, _ = a.GetAvgMergeTime(ctx, repo)
-		}()
+// checkRepo computes all metrics of a single repo, running each metric in its own goroutine.
+// Metrics that fail are left at zero and their errors recorded in RepoMetrics.Errors, keyed by metric name.
+// Metrics disabled in EnabledMetrics aren't run. With FailFastThreshold, a repo whose requests keep failing is
+// aborted: its remaining metrics fail with ErrRepoAborted and it's marked Failed.
+func (a *Analyzer) checkRepo(ctx context.Context, repo string) RepoMetrics {
+	m := RepoMetrics{Repo: repo, Area: a.AreaForRepo(repo)}
+	m.Owner, _ = a.splitRepo(repo)
+	m.PeriodFrom = a.StartDate.Format("02-01-2006")
+	m.PeriodTo = a.EndDate.Format("02-01-2006")
 
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			m.AvgReviewersPerPR, m.CrossTeamReviews, _ = a.GetAvgReviewersPerPR(ctx, repo)
-		}()
+	ctx, stop := a.withBreaker(ctx)
+	defer stop()
 
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			m.ChurnByFile, _ = a.GetChurnByFile(ctx, repo)
-		}()
+	// Archived and empty repos have nothing to measure, and an empty one fails most requests,
+	// as does one the token can't see
+	info, err := a.getRepository(ctx, repo)
+	if errors.Is(err, ErrUnauthorizedRepo) {
+		m.Skipped, m.SkipReason = true, "unauthorized"
+		return m
+	}
+	if err == nil {
+		m.IsFork = info.GetFork()
+		switch {
+		case info.GetArchived():
+			m.Skipped, m.SkipReason = true, "archived"
+			return m
+		case info.GetSize() == 0:
+			m.Skipped, m.SkipReason = true, "empty"
+			return m
+		}
+	}
 
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			m.ChurnByDir, _ = a.GetChurnByDir(ctx, repo)
-		}()
+	var wg sync.WaitGroup
+	var mu sync.Mutex
 
+	// run computes a metric in its own goroutine. The metric returns a func that stores its
+	// results, which is called with m locked, so no two goroutines write to m at the same time.
+	run := func(metric string, compute func() (store func(), err error)) {
+		if enabled, ok := a.EnabledMetrics[metric]; ok && !enabled {
+			return
+		}
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			m.IntegrationIssues, _ = a.GetIntegrationIssues(ctx, repo) // ← função não mostrada ainda
-		}()
+			start := time.Now()
+			store, err := compute()
 
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			m.RevertRate, _ = a.GetRevertRate(ctx, repo) // ← função não mostrada
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if m.Errors == nil {
+					m.Errors = make(map[string]string)
+				}
+				m.Errors[metric] = err.Error()
+				if interruptedBy(err) {
+					m.interrupted = true
+				}
+				a.logEvent(slog.LevelWarn, "metric failed", "repo", repo, "metric", metric, "duration", time.Since(start), "error", err)
+				return
+			}
+			store()
+			a.logEvent(slog.LevelDebug, "metric done", "repo", repo, "metric", metric, "duration", time.Since(start))
 		}()
+	}
 
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			m.MainBranchSizeBytes, m.MainFileCount, _ = a.GetMainSize(ctx, repo)
-		}()
+	run("UniqueContributors", func() (func(), error) {
+		count, list, err := a.GetUniqueContributors(ctx, repo)
+		return func() { m.UniqueContributors, m.ContributorsList = count, list }, err
+	})
 
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			m.SuccessfulReruns, _ = a.GetSuccessfulReruns(ctx, repo)
-		}()
+	run("CommitDistribution", func() (func(), error) {
+		dist, err := a.GetCommitDistribution(ctx, repo)
+		return func() { m.CommitDist = dist }, err
+	})
 
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			m.RollbackIssues, _ = a.GetRollbackIssues(ctx, repo)
-		}()
+	run("BusFactor", func() (func(), error) {
+		busFactor, err := a.GetBusFactor(ctx, repo)
+		return func() { m.BusFactor = busFactor }, err
+	})
 
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			m.WorkflowFailures, _ = a.GetWorkflowFailures(ctx, repo) // ← função não mostrada
-		}()
+	run("CommitTypeDistribution", func() (func(), error) {
+		dist, err := a.GetCommitTypeDistribution(ctx, repo)
+		return func() { m.CommitTypeDist = dist }, err
+	})
 
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			m.SuccessfulDeploys, _ = a.GetSuccessfulDeploys(ctx, repo) // ← função não mostrada
-		}()
+	run("CommitActivityHeatmap", func() (func(), error) {
+		heatmap, err := a.GetCommitActivityHeatmap(ctx, repo)
+		return func() { m.CommitActivityHeatmap = heatmap }, err
+	})
 
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			m.AvgThreadDepth, _ = a.GetAvgThreadDepth(ctx, repo)
-		}()
+	run("UnlinkedCommits", func() (func(), error) {
+		count, byEmail, err := a.GetUnlinkedCommits(ctx, repo)
+		return func() { m.UnlinkedCommits, m.UnlinkedCommitsByEmail = count, byEmail }, err
+	})
 
-		wg.Wait()
+	run("DirectPushCount", func() (func(), error) {
+		count, err := a.GetDirectPushCount(ctx, repo)
+		return func() { m.DirectPushCount = count }, err
+	})
 
-		metrics = append(metrics, m)
+	run("ConflictRateAndCount", func() (func(), error) {
+		rate, count, err := a.GetConflictRateAndCount(ctx, repo)
+		return func() { m.ConflictRate, m.ConflictMergesCount = rate, count }, err
+	})
+
+	run("ResolvedConflictCount", func() (func(), error) {
+		count, err := a.GetResolvedConflictCount(ctx, repo)
+		return func() { m.ResolvedConflicts = count }, err
+	})
+
+	run("AvgMergeTime", func() (func(), error) {
+		days, merged, err := a.GetAvgMergeTime(ctx, repo)
+		return func() { m.AvgMergeTimeDays, m.MergedPRs = days, merged }, err
+	})
+
+	run("PRMergeStats", func() (func(), error) {
+		_, closedUnmerged, _, err := a.GetPRMergeStats(ctx, repo)
+		return func() { m.ClosedUnmergedPRs = closedUnmerged }, err
+	})
+
+	run("PRThroughput", func() (func(), error) {
+		perWeek, err := a.GetPRThroughput(ctx, repo)
+		return func() { m.PRsMergedPerWeek = perWeek }, err
+	})
+
+	run("MergeTimePercentiles", func() (func(), error) {
+		p50, p90, _, err := a.GetMergeTimePercentiles(ctx, repo)
+		return func() { m.MergeTimeP50Days, m.MergeTimeP90Days = p50, p90 }, err
+	})
+
+	run("AvgReviewersPerPR", func() (func(), error) {
+		avg, crossTeam, err := a.GetAvgReviewersPerPR(ctx, repo)
+		return func() { m.AvgReviewersPerPR, m.CrossTeamReviews = avg, crossTeam }, err
+	})
+
+	run("ReviewOutcomeRates", func() (func(), error) {
+		approve, changes, err := a.GetReviewOutcomeRates(ctx, repo)
+		return func() { m.ApprovalRate, m.ChangesRequestedRate = approve, changes }, err
+	})
+
+	run("ReviewerLoad", func() (func(), error) {
+		load, err := a.GetReviewerLoad(ctx, repo)
+		return func() { m.ReviewerLoad = load }, err
+	})
+
+	run("ReviewerLatency", func() (func(), error) {
+		latency, err := a.GetReviewerLatency(ctx, repo)
+		return func() {
+			m.ReviewerLatency = make(map[string]float64, len(latency))
+			for login, d := range latency {
+				m.ReviewerLatency[login] = d.Hours()
+			}
+		}, err
+	})
+
+	run("ApprovedNotMergedCount", func() (func(), error) {
+		count, wait, err := a.GetApprovedNotMergedCount(ctx, repo)
+		return func() { m.ApprovedNotMergedCount, m.AvgWaitAfterApprovalHours = count, wait }, err
+	})
+
+	// GraphQL-only metrics cost a full GraphQL PR listing (shared by both), so they only run when the
+	// other PR metrics use it too
+	if a.UseGraphQL {
+		run("ReviewThreadResolutionRate", func() (func(), error) {
+			rate, err := a.GetReviewThreadResolutionRate(ctx, repo)
+			return func() { m.ThreadResolutionRate = rate }, err
+		})
+	}
+
+	run("AvgPRSize", func() (func(), error) {
+		files, lines, err := a.GetAvgPRSize(ctx, repo)
+		return func() { m.AvgPRChangedFiles, m.AvgPRLinesChanged = files, lines }, err
+	})
+
+	run("PRLinesChangedPercentile", func() (func(), error) {
+		p90, err := a.GetPRLinesChangedPercentile(ctx, repo, 90)
+		return func() { m.PRLinesChangedP90 = p90 }, err
+	})
+
+	run("UnreviewedMerges", func() (func(), error) {
+		count, err := a.GetUnreviewedMerges(ctx, repo)
+		return func() { m.UnreviewedMerges = count }, err
+	})
+
+	run("SelfMerges", func() (func(), error) {
+		count, err := a.GetSelfMerges(ctx, repo)
+		return func() { m.SelfMerges = count }, err
+	})
+
+	run("MergeMethodDistribution", func() (func(), error) {
+		dist, err := a.GetMergeMethodDistribution(ctx, repo)
+		return func() { m.MergeMethodDist = dist }, err
+	})
+
+	run("ChurnByFile", func() (func(), error) {
+		// computed once: churn by dir and by extension are derived from it
+		churn, err := a.GetChurnByFile(ctx, repo)
+		return func() {
+			m.ChurnByFile = churn
+			m.ChurnByDir = churnByDir(churn)
+			m.ChurnByExtension = churnByExtension(churn)
+		}, err
+	})
+
+	run("LineChurnByFile", func() (func(), error) {
+		churn, err := a.GetLineChurnByFile(ctx, repo)
+		return func() { m.LineChurnByFile = churn }, err
+	})
+
+	run("CodeVolume", func() (func(), error) {
+		additions, deletions, err := a.GetCodeVolume(ctx, repo)
+		return func() { m.TotalAdditions, m.TotalDeletions = additions, deletions }, err
+	})
+
+	run("IntegrationIssues", func() (func(), error) {
+		count, err := a.GetIntegrationIssues(ctx, repo)
+		return func() { m.IntegrationIssues = count }, err
+	})
+
+	if len(a.TrackedLabels) > 0 {
+		run("IssueCountsByLabel", func() (func(), error) {
+			counts, err := a.GetIssueCountsByLabel(ctx, repo, a.TrackedLabels)
+			return func() { m.IssueCountsByLabel = counts }, err
+		})
+	}
+
+	run("RevertRate", func() (func(), error) {
+		rate, err := a.GetRevertRate(ctx, repo)
+		return func() { m.RevertRate = rate }, err
+	})
+
+	run("MainSize", func() (func(), error) {
+		size, files, err := a.GetMainSize(ctx, repo)
+		return func() { m.MainBranchSizeBytes, m.MainFileCount = size, files }, err
+	})
+
+	run("SuccessfulReruns", func() (func(), error) {
+		count, err := a.GetSuccessfulReruns(ctx, repo)
+		return func() { m.SuccessfulReruns = count }, err
+	})
+
+	run("RollbackIssues", func() (func(), error) {
+		count, err := a.GetRollbackIssues(ctx, repo)
+		return func() { m.RollbackIssues = count }, err
+	})
+
+	run("WorkflowFailures", func() (func(), error) {
+		count, err := a.GetWorkflowFailures(ctx, repo)
+		return func() { m.WorkflowFailures = count }, err
+	})
+
+	run("SuccessfulDeploys", func() (func(), error) {
+		count, err := a.GetSuccessfulDeploys(ctx, repo)
+		return func() { m.SuccessfulDeploys = count }, err
+	})
+
+	run("WorkflowFailuresByName", func() (func(), error) {
+		counts, err := a.GetWorkflowFailuresByName(ctx, repo)
+		return func() { m.WorkflowFailuresByName = counts }, err
+	})
+
+	run("SuccessfulDeploysByName", func() (func(), error) {
+		counts, err := a.GetSuccessfulDeploysByName(ctx, repo)
+		return func() { m.SuccessfulDeploysByName = counts }, err
+	})
+
+	run("AvgWorkflowDuration", func() (func(), error) {
+		avg, err := a.GetAvgWorkflowDuration(ctx, repo)
+		seconds := int(avg.Seconds())
+		return func() { m.AvgWorkflowDurationSeconds, m.AvgWorkflowDuration = seconds, a.FormatSecondsToHMS(seconds) }, err
+	})
+
+	run("WorkflowDurationHistogram", func() (func(), error) {
+		histogram, err := a.GetWorkflowDurationHistogram(ctx, repo)
+		return func() { m.WorkflowDurationHistogram = histogram }, err
+	})
+
+	run("DeploymentFrequency", func() (func(), error) {
+		perDay, err := a.GetDeploymentFrequency(ctx, repo)
+		return func() { m.DeploymentsPerDay = perDay }, err
+	})
+
+	run("LeadTimeForChanges", func() (func(), error) {
+		leadTime, err := a.GetLeadTimeForChanges(ctx, repo)
+		return func() { m.LeadTimeForChangesHours = leadTime.Hours() }, err
+	})
+
+	run("ChangeFailureRate", func() (func(), error) {
+		rate, err := a.GetChangeFailureRate(ctx, repo)
+		return func() { m.ChangeFailureRate = rate }, err
+	})
+
+	run("MTTR", func() (func(), error) {
+		mttr, err := a.GetMTTR(ctx, repo)
+		return func() { m.MTTRHours = mttr.Hours() }, err
+	})
+
+	run("IssueThroughput", func() (func(), error) {
+		opened, closed, resolution, err := a.GetIssueThroughput(ctx, repo)
+		return func() {
+			m.IssuesOpened, m.IssuesClosed = opened, closed
+			m.AvgIssueResolutionHours = resolution.Hours()
+		}, err
+	})
+
+	run("AvgTimeToFirstReview", func() (func(), error) {
+		firstReview, err := a.GetAvgTimeToFirstReview(ctx, repo)
+		return func() { m.AvgTimeToFirstReviewHours = firstReview.Hours() }, err
+	})
+
+	if a.CollectDetails {
+		run("RepoDetails", func() (func(), error) {
+			details, err := a.GetRepoDetails(ctx, repo)
+			return func() { m.Details = details }, err
+		})
 	}
 
-	return metrics, nil
+	run("StalePRCount", func() (func(), error) {
+		count, err := a.GetStalePRCount(ctx, repo, a.StaleThreshold)
+		return func() { m.StalePRCount = count }, err
+	})
+
+	run("AvgCommentsPerItem", func() (func(), error) {
+		avg, err := a.GetAvgCommentsPerItem(ctx, repo)
+		return func() { m.AvgCommentsPerItem = avg }, err
+	})
+
+	if a.UseGraphQL {
+		run("MaxThreadDepth", func() (func(), error) {
+			depth, err := a.GetMaxThreadDepth(ctx, repo)
+			return func() { m.MaxThreadDepth = depth }, err
+		})
+	}
+
+	wg.Wait()
+
+	if errors.Is(context.Cause(ctx), ErrRepoAborted) {
+		m.Failed = true
+		a.logEvent(slog.LevelWarn, "repo aborted", "repo", repo, "consecutive_failures", a.FailFastThreshold)
+	} else if ctx.Err() != nil {
+		m.interrupted = true
+	}
+	return m
+}
+
+// interruptedBy reports whether a metric error comes from the run rather than from the repo: the run was
+// canceled or ran out of requests, or GitHub rate limited it. Running the metric again later may succeed.
+func interruptedBy(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, ErrRequestBudgetExceeded) || errors.Is(err, ErrRateLimited)
 }
@@ -0,0 +1,55 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// TestCheckConcurrentRepos runs the metrics of several repos at once; run it with -race.
+func TestCheckConcurrentRepos(t *testing.T) {
+	p := &fakeProvider{repos: make(map[string]*fakeRepo)}
+	var repos []string
+	for i := 1; i <= 6; i++ {
+		name := fmt.Sprintf("svc-%d", i)
+		repos = append(repos, name)
+		// svc-i has i commits by its own author
+		r := &fakeRepo{headSHA: "head-" + name, tree: []*github.TreeEntry{{Type: github.String("blob"), Size: github.Int(10 * i)}}}
+		for c := 0; c < i; c++ {
+			r.commits = append(r.commits, fakeCommit(fmt.Sprintf("%s-c%d", name, c), "dev-"+name, "", "fix: x", 1+c, 1))
+		}
+		r.prs = []*github.PullRequest{{
+			Number:         github.Int(1),
+			State:          github.String("closed"),
+			MergeableState: github.String("clean"),
+			CreatedAt:      &github.Timestamp{Time: testStart.AddDate(0, 0, 2)},
+			UpdatedAt:      &github.Timestamp{Time: testStart.AddDate(0, 0, 3)},
+		}}
+		p.repos[name] = r
+	}
+	a := newFakeAnalyzer(p, map[string][]string{"platform": repos})
+	a.RepoConcurrency = len(repos)
+
+	metrics, err := a.Check(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(metrics) != len(repos) {
+		t.Fatalf("Check returned %d repos, want %d", len(metrics), len(repos))
+	}
+	for i, m := range metrics {
+		name := fmt.Sprintf("svc-%d", i+1)
+		if m.Repo != name {
+			t.Errorf("metrics[%d].Repo = %q, want %q", i, m.Repo, name)
+			continue
+		}
+		if got := m.CommitDist["dev-"+name]; got != i+1 || len(m.CommitDist) != 1 {
+			t.Errorf("%s: CommitDist = %v, want %d commits by dev-%s", name, m.CommitDist, i+1, name)
+		}
+		if m.MainBranchSizeBytes != int64(10*(i+1)) {
+			t.Errorf("%s: MainBranchSizeBytes = %d, want %d", name, m.MainBranchSizeBytes, 10*(i+1))
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package analyzer
+
+import (
+	"sync"
+	"time"
+)
+
+// Cache stores values computed from immutable GitHub data, such as the git tree of a commit.
+// Implementations must be safe for concurrent use; plug in your own to share results across processes.
+type Cache interface {
+	Get(key string) (any, bool)
+	Set(key string, value any)
+}
+
+// MemoryCache is an in-process Cache whose entries expire after a fixed TTL.
+type MemoryCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	value     any
+	expiresAt time.Time
+}
+
+// NewMemoryCache creates a MemoryCache keeping entries for ttl.
+func NewMemoryCache(ttl time.Duration) *MemoryCache {
+	return &MemoryCache{ttl: ttl, entries: make(map[string]cacheEntry)}
+}
+
+// Get returns the value stored for key, if present and not expired.
+func (c *MemoryCache) Get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.value, true
+}
+
+// Set stores value for key until the TTL elapses.
+func (c *MemoryCache) Set(key string, value any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = cacheEntry{value: value, expiresAt: time.Now().Add(c.ttl)}
+}
@@ -0,0 +1,115 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// DefaultCheckpointFile is the checkpoint file used by the CLI's -resume flag.
+const DefaultCheckpointFile = ".checkpoint.json"
+
+// checkpoint is the on-disk progress of a CheckResumable run.
+type checkpoint struct {
+	Key   string        `json:"key"` // owner and period the results belong to
+	Repos []RepoMetrics `json:"repos"`
+}
+
+// CheckResumable works like Check, but saves each finished repo to the checkpoint file at path.
+// If the file already holds results for the same owner and period, those repos aren't analyzed again,
+// so an interrupted run can be resumed; results of another period are ignored and overwritten.
+// Repos that didn't finish are returned but not saved, so the next run retries them: repos marked Failed,
+// and those with a metric cut short by cancellation, MaxRequests or the rate limit.
+// The checkpoint is removed once every repo is done.
+func (a *Analyzer) CheckResumable(ctx context.Context, path string) ([]RepoMetrics, error) {
+	key := a.checkpointKey()
+	cp, err := loadCheckpoint(path)
+	if err != nil {
+		return nil, err
+	}
+	if cp.Key != key {
+		cp = checkpoint{Key: key}
+	}
+
+	done := make(map[string]struct{})
+	for _, m := range cp.Repos {
+		done[m.Repo] = struct{}{}
+	}
+	var pending []string
+	for _, repos := range a.Projects {
+		for _, repo := range repos {
+			if _, ok := done[repo]; !ok {
+				pending = append(pending, repo)
+			}
+		}
+	}
+
+	var unfinished []RepoMetrics
+	results, errs := a.stream(ctx, pending)
+	for m := range results {
+		if m.Failed || m.interrupted {
+			unfinished = append(unfinished, m)
+			continue
+		}
+		cp.Repos = append(cp.Repos, m)
+		if err := saveCheckpoint(path, cp); err != nil {
+			// keep draining so the workers can finish
+			for range results {
+			}
+			return nil, err
+		}
+	}
+	all := append(cp.Repos, unfinished...)
+	if err := <-errs; err != nil {
+		return all, err
+	}
+
+	metrics := sortMetrics(all)
+	if len(unfinished) > 0 {
+		// keep the checkpoint for the next run to retry them
+		a.logEvent(slog.LevelWarn, "repos left for the next run", "count", len(unfinished), "checkpoint", path)
+		return metrics, nil
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return metrics, err
+	}
+	return metrics, nil
+}
+
+// checkpointKey identifies the owner and period of a run.
+func (a *Analyzer) checkpointKey() string {
+	return fmt.Sprintf("%s:%s..%s", a.Owner, a.StartDate.Format("2006-01-02T15:04:05Z07:00"), a.EndDate.Format("2006-01-02T15:04:05Z07:00"))
+}
+
+// loadCheckpoint reads the checkpoint at path; a missing file is an empty checkpoint.
+func loadCheckpoint(path string) (checkpoint, error) {
+	var cp checkpoint
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cp, nil
+	}
+	if err != nil {
+		return cp, err
+	}
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return cp, fmt.Errorf("invalid checkpoint %s: %w", path, err)
+	}
+	return cp, nil
+}
+
+// saveCheckpoint writes the checkpoint through a temporary file, so a crash never leaves it half written.
+func saveCheckpoint(path string, cp checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,64 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckResumableRetriesUnfinishedRepos(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+	}{
+		{"aborted repo", http.StatusBadGateway},
+		{"rate limited repo", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "checkpoint.json")
+			p := &fakeProvider{
+				repos: map[string]*fakeRepo{"api": {}, "web": {}},
+				fail:  map[string]int{"web": tt.status},
+			}
+			a := newFakeAnalyzer(p, map[string][]string{"core": {"api", "web"}})
+			a.FailFastThreshold = 2
+			ctx := context.Background()
+
+			metrics, err := a.CheckResumable(ctx, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(metrics) != 2 || len(metrics[1].Errors) == 0 {
+				t.Fatalf("first run: want api and a failing web, got %+v", metrics)
+			}
+			cp, err := loadCheckpoint(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(cp.Repos) != 1 || cp.Repos[0].Repo != "api" {
+				t.Fatalf("first run: checkpoint holds %+v, want only api", cp.Repos)
+			}
+
+			// web recovers: resuming only analyzes it again
+			p.fail = nil
+			before := p.callsTo("GetRepository")
+			metrics, err = a.CheckResumable(ctx, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := p.callsTo("GetRepository") - before; got != 1 {
+				t.Errorf("resume analyzed %d repos, want 1 (web)", got)
+			}
+			if len(metrics) != 2 || metrics[1].Repo != "web" || len(metrics[1].Errors) > 0 || metrics[1].Failed {
+				t.Errorf("resume: want api and a complete web, got %+v", metrics)
+			}
+			if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("checkpoint still there after every repo finished: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// MergeMetrics reads the metrics exported by several runs (e.g. one per area or per token) and combines
+// them into a single list, so a large organization can be sharded and its reports recombined.
+// Files may hold a plain JSON array (Export) or a Report envelope (ExportV2).
+// A repo found in several files keeps the metrics of the last one, at the position of its first
+// appearance; it's an error if the copies cover different periods.
+func MergeMetrics(files []string) ([]RepoMetrics, error) {
+	var merged []RepoMetrics
+	index := make(map[string]int) // owner/repo -> position in merged
+	source := make(map[string]string)
+	for _, file := range files {
+		metrics, err := readMetrics(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range metrics {
+			key := m.Owner + "/" + m.Repo
+			i, ok := index[key]
+			if !ok {
+				index[key] = len(merged)
+				source[key] = file
+				merged = append(merged, m)
+				continue
+			}
+			if prev := merged[i]; prev.PeriodFrom != m.PeriodFrom || prev.PeriodTo != m.PeriodTo {
+				return nil, fmt.Errorf("%s covers %s..%s in %s but %s..%s in %s",
+					key, prev.PeriodFrom, prev.PeriodTo, source[key], m.PeriodFrom, m.PeriodTo, file)
+			}
+			merged[i] = m
+			source[key] = file
+		}
+	}
+	return merged, nil
+}
+
+// readMetrics reads the metrics of a file written by Export or ExportV2.
+func readMetrics(file string) ([]RepoMetrics, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var metrics []RepoMetrics
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		var report Report
+		err = json.Unmarshal(data, &report)
+		metrics = report.Repos
+	} else {
+		err = json.Unmarshal(data, &metrics)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid metrics file %s: %w", file, err)
+	}
+	return metrics, nil
+}
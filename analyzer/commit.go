@@ -2,26 +2,61 @@ package analyzer
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/go-github/v62/github"
 )
 
 // GetMainSize returns the size and file count of the default branch.
 func (a *Analyzer) GetMainSize(ctx context.Context, repo string) (int64, int, error) {
-	ref, _, err := a.client.Git.GetRef(ctx, a.Owner, repo, "heads/"+a.DefaultBranch)
+	return a.GetBranchSize(ctx, repo, a.DefaultBranch)
+}
+
+// GetBranchSize returns the size and file count of the given branch.
+// The HEAD commit is looked up once per run and the tree cached per HEAD commit, so it's only fetched
+// again when the branch moves.
+func (a *Analyzer) GetBranchSize(ctx context.Context, repo, branch string) (int64, int, error) {
+	owner, name := a.splitRepo(repo)
+	commitSHA, err := memoize(a.memo, a.memoKey("head#"+branch, repo), func() (string, error) {
+		ref, _, err := doRequest(ctx, a, func() (*github.Reference, *github.Response, error) {
+			return a.provider.GetRef(ctx, owner, name, "heads/"+branch)
+		})
+		if err != nil {
+			var errResp *github.ErrorResponse
+			if errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusNotFound {
+				return "", fmt.Errorf("branch %q not found in %s/%s", branch, owner, name)
+			}
+			return "", err
+		}
+		return ref.GetObject().GetSHA(), nil
+	})
 	if err != nil {
 		return 0, 0, err
 	}
 
-	commitSHA := *ref.Object.SHA
+	cacheKey := fmt.Sprintf("tree:%s/%s@%s", owner, name, commitSHA)
+	if a.Cache != nil {
+		if v, ok := a.Cache.Get(cacheKey); ok {
+			if size, ok := v.(treeSize); ok {
+				return size.Bytes, size.Files, nil
+			}
+		}
+	}
 
-	tree, resp, err := a.client.Git.GetTree(ctx, a.Owner, repo, commitSHA, true) // true = recursive
+	tree, _, err := doRequest(ctx, a, func() (*github.Tree, *github.Response, error) {
+		return a.provider.GetTree(ctx, owner, name, commitSHA, true) // true = recursive
+	})
 	if err != nil {
 		return 0, 0, err
 	}
-	a.checkRateLimit(resp)
 
 	var totalSize int64
 	fileCount := 0
@@ -35,147 +70,486 @@ func (a *Analyzer) GetMainSize(ctx context.Context, repo string) (int64, int, er
 		}
 	}
 
+	if a.Cache != nil {
+		a.Cache.Set(cacheKey, treeSize{Bytes: totalSize, Files: fileCount})
+	}
+
 	return totalSize, fileCount, nil
 }
 
+// treeSize is the cached result of a tree walk in GetBranchSize.
+type treeSize struct {
+	Bytes int64
+	Files int
+}
+
 // GetCommitDistribution returns the distribution of commits by contributor for a repo in the period.
+// As in GetUniqueContributors, bots are left out when ExcludeBots is set. Commits without a linked
+// GitHub account are skipped, or with AttributeByEmail counted under "email:<git author e-mail>".
 func (a *Analyzer) GetCommitDistribution(ctx context.Context, repo string) (map[string]int, error) {
-	opts := &github.CommitsListOptions{
-		Since:       a.StartDate,
-		Until:       a.EndDate,
-		ListOptions: github.ListOptions{PerPage: 100},
+	commits, err := a.getCommits(ctx, repo)
+	if err != nil {
+		return nil, err
 	}
 
 	dist := make(map[string]int)
-
-	for {
-		commits, resp, err := a.client.Repositories.ListCommits(ctx, a.Owner, repo, opts)
-		if err != nil {
-			return nil, err
-		}
-
-		for _, c := range commits {
-			if c.Author != nil && c.Author.Login != nil {
+	for _, c := range commits {
+		if c.Author != nil && c.Author.Login != nil {
+			if !a.excludedAuthor(*c.Author.Login) {
 				dist[*c.Author.Login]++
 			}
+			continue
 		}
-
-		if resp.NextPage == 0 {
-			break
+		if a.AttributeByEmail && c.Author == nil {
+			if email := c.GetCommit().GetAuthor().GetEmail(); email != "" {
+				dist["email:"+email]++
+			}
 		}
-		opts.Page = resp.NextPage
-		a.checkRateLimit(resp)
 	}
 
 	return dist, nil
 }
 
-// GetConflictRateAndCount returns the rate and count of PRs with merge conflicts for a repo in the period.
-func (a *Analyzer) GetConflictRateAndCount(ctx context.Context, repo string) (float64, int, error) {
-	opts := &github.PullRequestListOptions{State: "all", Sort: "created", Direction: "desc", ListOptions: github.ListOptions{PerPage: 100}}
-	var allPRs []*github.PullRequest
+// GetCommitTypeDistribution returns the number of commits in the period by Conventional Commits type
+// ("feat", "fix", "chore"...). Commits not following the convention are counted under "other".
+func (a *Analyzer) GetCommitTypeDistribution(ctx context.Context, repo string) (map[string]int, error) {
+	commits, err := a.getCommits(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	dist := make(map[string]int)
+	for _, c := range commits {
+		dist[commitType(c.GetCommit().GetMessage())]++
+	}
+	return dist, nil
+}
+
+// GetCommitActivityHeatmap returns the number of commits in the period by weekday and hour of their
+// author date in a.Timezone (UTC when nil), keyed by "Mon-14" style buckets (hour 00-23).
+// Empty buckets are left out.
+func (a *Analyzer) GetCommitActivityHeatmap(ctx context.Context, repo string) (map[string]int, error) {
+	commits, err := a.getCommits(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := a.timezone()
+	heatmap := make(map[string]int)
+	for _, c := range commits {
+		date := c.GetCommit().GetAuthor().GetDate()
+		if date.IsZero() {
+			continue
+		}
+		t := date.In(loc)
+		heatmap[fmt.Sprintf("%s-%02d", t.Format("Mon"), t.Hour())]++
+	}
+	return heatmap, nil
+}
+
+// getCommits returns the commits of the default branch in the period. With ExcludeMergeCommits, commits with
+// more than one parent are left out; with TreatForksSpecially, only the commits of a fork that aren't on its
+// parent are returned (see forkOnlyCommits).
+// The list is fetched once per repo and period and shared by every commit-based metric.
+func (a *Analyzer) getCommits(ctx context.Context, repo string) ([]*github.RepositoryCommit, error) {
+	owner, name := a.splitRepo(repo)
+	return memoize(a.memo, a.memoKey("commits", repo), func() ([]*github.RepositoryCommit, error) {
+		opts := &github.CommitsListOptions{
+			Since:       a.StartDate,
+			Until:       a.EndDate,
+			ListOptions: github.ListOptions{PerPage: 100},
+		}
+
+		var commits []*github.RepositoryCommit
+		for {
+			cs, resp, err := doRequest(ctx, a, func() ([]*github.RepositoryCommit, *github.Response, error) {
+				return a.provider.ListCommits(ctx, owner, name, opts)
+			})
+			if err != nil {
+				return nil, err
+			}
+			commits = append(commits, cs...)
+			if a.lastPage(resp, opts.Page) {
+				break
+			}
+			opts.Page = resp.NextPage
+		}
+
+		if a.ExcludeMergeCommits {
+			own := commits[:0]
+			for _, c := range commits {
+				if len(c.Parents) <= 1 {
+					own = append(own, c)
+				}
+			}
+			commits = own
+		}
+		if a.TreatForksSpecially {
+			return a.forkOnlyCommits(ctx, repo, commits)
+		}
+		return commits, nil
+	})
+}
+
+// forkOnlyCommits keeps the commits that are unique to a fork, i.e. not on the default branch of its parent,
+// so commits synced from upstream don't count. Repos that aren't forks are returned unchanged.
+// The unique commits come from comparing the parent's default branch with the fork's, which GitHub caps
+// at 250 commits (extra pages aside): forks far ahead of their parent may lose their oldest commits.
+func (a *Analyzer) forkOnlyCommits(ctx context.Context, repo string, commits []*github.RepositoryCommit) ([]*github.RepositoryCommit, error) {
+	owner, name := a.splitRepo(repo)
+	info, err := a.getRepository(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	parent := info.GetParent()
+	if !info.GetFork() || parent == nil {
+		return commits, nil
+	}
+
+	base := parent.GetOwner().GetLogin() + ":" + parent.GetDefaultBranch()
+	unique := make(map[string]struct{})
+	opts := &github.ListOptions{PerPage: 100}
 	for {
-		prs, resp, err := a.client.PullRequests.List(ctx, a.Owner, repo, opts)
+		cmp, resp, err := doRequest(ctx, a, func() (*github.CommitsComparison, *github.Response, error) {
+			return a.provider.CompareCommits(ctx, owner, name, base, info.GetDefaultBranch(), opts)
+		})
 		if err != nil {
-			return 0, 0, err
+			return nil, err
 		}
-		for _, pr := range prs {
-			if pr.CreatedAt.After(a.StartDate) && pr.CreatedAt.Before(a.EndDate) {
-				allPRs = append(allPRs, pr)
-			}
+		for _, c := range cmp.Commits {
+			unique[c.GetSHA()] = struct{}{}
 		}
-		if resp.NextPage == 0 {
+		if a.lastPage(resp, opts.Page) {
 			break
 		}
 		opts.Page = resp.NextPage
-		a.checkRateLimit(resp)
+	}
+
+	var own []*github.RepositoryCommit
+	for _, c := range commits {
+		if _, ok := unique[c.GetSHA()]; ok {
+			own = append(own, c)
+		}
+	}
+	return own, nil
+}
+
+// GetDirectPushCount returns the number of commits on the default branch in the period that don't belong
+// to any merged PR, i.e. changes pushed straight to the branch. Merge commits are left out.
+// It needs one request per commit.
+func (a *Analyzer) GetDirectPushCount(ctx context.Context, repo string) (int, error) {
+	owner, name := a.splitRepo(repo)
+	commits, err := a.getCommits(ctx, repo)
+	if err != nil {
+		return 0, err
+	}
+
+	direct := 0
+	var mu sync.Mutex
+	g, gctx := a.innerGroup(ctx)
+	for _, c := range commits {
+		if c.SHA == nil || len(c.Parents) > 1 {
+			continue
+		}
+		if gctx.Err() != nil {
+			break
+		}
+		sha := *c.SHA
+		g.Go(func() error {
+			prs, _, err := doRequest(gctx, a, func() ([]*github.PullRequest, *github.Response, error) {
+				return a.provider.ListPullRequestsWithCommit(gctx, owner, name, sha, &github.ListOptions{PerPage: 100})
+			})
+			if err != nil {
+				return hardError(err)
+			}
+			for _, pr := range prs {
+				if pr.MergedAt != nil {
+					return nil
+				}
+			}
+			mu.Lock()
+			direct++
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return 0, err
+	}
+
+	return direct, nil
+}
+
+// GetConflictRateAndCount returns the rate and count of PRs with merge conflicts for a repo in the period.
+// Only PRs whose mergeable_state is "dirty" count as conflicts; PRs whose state is still unknown after
+// retrying are left out of the rate.
+func (a *Analyzer) GetConflictRateAndCount(ctx context.Context, repo string) (float64, int, error) {
+	if a.UseGraphQL && a.gql != nil {
+		return a.conflictRateAndCountGraphQL(ctx, repo)
+	}
+
+	allPRs, err := a.listPullRequests(ctx, repo, "all")
+	if err != nil {
+		return 0, 0, err
 	}
 
 	var mu sync.Mutex
 	conflicts := 0
-	wg := sync.WaitGroup{}
-	sem := make(chan struct{}, 10)
+	known := 0
+	g, gctx := a.innerGroup(ctx)
 	for _, pr := range allPRs {
-		wg.Add(1)
-		go func(pr *github.PullRequest) {
-			defer wg.Done()
-			sem <- struct{}{}
-			defer func() { <-sem }()
-			fullPR, resp, err := a.client.PullRequests.Get(ctx, a.Owner, repo, *pr.Number)
-			if err == nil && fullPR.Mergeable != nil && !*fullPR.Mergeable {
-				mu.Lock()
+		if gctx.Err() != nil {
+			break
+		}
+		g.Go(func() error {
+			state, err := a.getMergeableState(gctx, repo, pr)
+			if err != nil {
+				return hardError(err)
+			}
+			if state == "unknown" {
+				return nil
+			}
+			mu.Lock()
+			known++
+			if state == "dirty" {
 				conflicts++
-				mu.Unlock()
 			}
-			a.checkRateLimit(resp)
-		}(pr)
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return 0, 0, err
 	}
-	wg.Wait()
 
-	if len(allPRs) == 0 {
+	if known == 0 {
 		return 0, 0, nil
 	}
-	rate := float64(conflicts) / float64(len(allPRs)) * 100
+	rate := float64(conflicts) / float64(known) * 100
 	return rate, conflicts, nil
 }
 
-// GetChurnByFile returns the churn rate by file for commits in the period.
-func (a *Analyzer) GetChurnByFile(ctx context.Context, repo string) (map[string]int, error) {
-	commitOpts := &github.CommitsListOptions{
-		Since:       a.StartDate,
-		Until:       a.EndDate,
-		ListOptions: github.ListOptions{PerPage: 100},
+// GetResolvedConflictCount returns the number of PRs merged in the period that had to be brought up to date
+// with the base branch before merging. The REST timeline has no conflict event, so a PR counts when its
+// timeline shows any of: a commit whose message lists "Conflicts:" (git's default for a conflicted merge),
+// a merge of the default branch into the PR (GitHub's conflict editor and "Update branch" both create one),
+// or a force-push of the head branch (a rebase). It's an upper bound: not every update or force-push was
+// resolving a conflict. It needs one timeline request per merged PR.
+func (a *Analyzer) GetResolvedConflictCount(ctx context.Context, repo string) (int, error) {
+	allPRs, err := a.listPullRequests(ctx, repo, "closed")
+	if err != nil {
+		return 0, err
+	}
+
+	resolved := 0
+	var mu sync.Mutex
+	g, gctx := a.innerGroup(ctx)
+	for _, pr := range allPRs {
+		if pr.MergedAt == nil {
+			continue
+		}
+		if gctx.Err() != nil {
+			break
+		}
+		prNum := pr.GetNumber()
+		g.Go(func() error {
+			events, err := a.listTimeline(gctx, repo, prNum)
+			if err != nil {
+				return hardError(err)
+			}
+			for _, e := range events {
+				if a.conflictResolutionEvent(e) {
+					mu.Lock()
+					resolved++
+					mu.Unlock()
+					return nil
+				}
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return 0, err
 	}
 
-	var commits []*github.RepositoryCommit
+	return resolved, nil
+}
 
-	for {
-		cs, resp, err := a.client.Repositories.ListCommits(ctx, a.Owner, repo, commitOpts)
+// conflictResolutionEvent reports whether a PR timeline event looks like the resolution of a merge conflict
+// (see GetResolvedConflictCount).
+func (a *Analyzer) conflictResolutionEvent(e *github.Timeline) bool {
+	switch e.GetEvent() {
+	case "head_ref_force_pushed":
+		return true
+	case "committed":
+		msg := e.GetMessage()
+		if strings.Contains(msg, "\nConflicts:") || strings.Contains(msg, "\n# Conflicts:") {
+			return true
+		}
+		return len(e.Parents) > 1 && (strings.HasPrefix(msg, fmt.Sprintf("Merge branch '%s' into ", a.DefaultBranch)) ||
+			strings.HasPrefix(msg, fmt.Sprintf("Merge remote-tracking branch 'origin/%s' into ", a.DefaultBranch)))
+	}
+	return false
+}
+
+// getMergeableState returns the mergeable_state of a PR ("dirty" means it has merge conflicts).
+// GitHub computes mergeability asynchronously, so an open PR reported as "unknown" is fetched again
+// up to mergeableRetries times, mergeableRetryDelay apart. Closed PRs are never recomputed and aren't retried.
+func (a *Analyzer) getMergeableState(ctx context.Context, repo string, pr *github.PullRequest) (string, error) {
+	owner, name := a.splitRepo(repo)
+	for attempt := 0; ; attempt++ {
+		var fullPR *github.PullRequest
+		var err error
+		if attempt == 0 {
+			// the first fetch is shared with the other metrics needing the full PR
+			fullPR, err = a.getPullRequest(ctx, repo, pr.GetNumber())
+		} else {
+			fullPR, _, err = doRequest(ctx, a, func() (*github.PullRequest, *github.Response, error) {
+				return a.provider.GetPullRequest(ctx, owner, name, pr.GetNumber())
+			})
+		}
 		if err != nil {
-			return nil, err
+			return "", err
 		}
-		commits = append(commits, cs...)
-		if resp.NextPage == 0 {
-			break
+
+		state := fullPR.GetMergeableState()
+		if state == "" {
+			state = "unknown"
+		}
+		if state != "unknown" || fullPR.GetState() != "open" || attempt >= mergeableRetries {
+			return state, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(mergeableRetryDelay):
 		}
-		commitOpts.Page = resp.NextPage
-		a.checkRateLimit(resp)
+	}
+}
+
+const (
+	mergeableRetries    = 3
+	mergeableRetryDelay = 2 * time.Second
+)
+
+// GetChurnByFile returns the churn rate by file for commits in the period, i.e. how many commits touched each file.
+// A one-line fix weighs the same as a rewrite; use GetLineChurnByFile to weigh files by lines changed instead.
+// Only files passing ChurnIncludeGlobs and ChurnExcludeGlobs are counted (see countsForChurn).
+func (a *Analyzer) GetChurnByFile(ctx context.Context, repo string) (map[string]int, error) {
+	files, err := a.getCommitFiles(ctx, repo)
+	if err != nil {
+		return nil, err
 	}
 
 	churn := make(map[string]int)
-	var mu sync.Mutex
-	wg := sync.WaitGroup{}
-	sem := make(chan struct{}, 10)
+	for _, f := range files {
+		if f.Filename != nil && a.countsForChurn(*f.Filename) {
+			churn[*f.Filename]++
+		}
+	}
+	return churn, nil
+}
 
-	for _, c := range commits {
-		if c.SHA == nil {
-			continue
+// GetLineChurnByFile returns the number of lines changed (additions + deletions) by file for commits in the period.
+// Unlike GetChurnByFile, large changes weigh more than small ones. ChurnIncludeGlobs and ChurnExcludeGlobs apply as well.
+func (a *Analyzer) GetLineChurnByFile(ctx context.Context, repo string) (map[string]int, error) {
+	files, err := a.getCommitFiles(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	churn := make(map[string]int)
+	for _, f := range files {
+		if f.Filename != nil && a.countsForChurn(*f.Filename) {
+			churn[*f.Filename] += f.GetAdditions() + f.GetDeletions()
 		}
-		wg.Add(1)
-		go func(sha string) {
-			defer wg.Done()
-			sem <- struct{}{}
-			defer func() { <-sem }()
-
-			// ← adicione nil como último argumento
-			full, resp, err := a.client.Repositories.GetCommit(ctx, a.Owner, repo, sha, nil)
-			if err == nil && full != nil && full.Files != nil {
-				mu.Lock()
-				for _, f := range full.Files {
-					if f.Filename != nil {
-						churn[*f.Filename]++
-					}
-				}
-				mu.Unlock()
+	}
+	return churn, nil
+}
+
+// GetCodeVolume returns the total lines added and deleted by the commits in the period.
+// It shares the per-commit fetch of the churn metrics, so it costs no extra requests when they run too.
+func (a *Analyzer) GetCodeVolume(ctx context.Context, repo string) (additions, deletions int, err error) {
+	files, err := a.getCommitFiles(ctx, repo)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, f := range files {
+		additions += f.GetAdditions()
+		deletions += f.GetDeletions()
+	}
+	return additions, deletions, nil
+}
+
+// getCommitFiles returns the files changed by every commit in the period, one entry per commit and file.
+// It needs one GetCommit request per commit, so the result is shared by every churn metric of a run.
+func (a *Analyzer) getCommitFiles(ctx context.Context, repo string) ([]*github.CommitFile, error) {
+	owner, name := a.splitRepo(repo)
+	return memoize(a.memo, a.memoKey("commit-files", repo), func() ([]*github.CommitFile, error) {
+		commits, err := a.getCommits(ctx, repo)
+		if err != nil {
+			return nil, err
+		}
+
+		var files []*github.CommitFile
+		var mu sync.Mutex
+		g, gctx := a.innerGroup(ctx)
+
+		for _, c := range commits {
+			if c.SHA == nil {
+				continue
 			}
-			if resp != nil {
-				a.checkRateLimit(resp)
+			if gctx.Err() != nil {
+				break
 			}
-		}(*c.SHA)
+			sha := *c.SHA
+			g.Go(func() error {
+				full, _, err := doRequest(gctx, a, func() (*github.RepositoryCommit, *github.Response, error) {
+					return a.provider.GetCommit(gctx, owner, name, sha, nil)
+				})
+				if err == nil && full != nil && full.Files != nil {
+					mu.Lock()
+					files = append(files, full.Files...)
+					mu.Unlock()
+				}
+				return hardError(err)
+			})
+		}
+		if err := g.Wait(); err != nil {
+			return nil, err
+		}
+
+		return files, nil
+	})
+}
+
+// countsForChurn reports whether a file counts toward the churn metrics: it must match one of ChurnIncludeGlobs
+// (when set) and none of ChurnExcludeGlobs.
+func (a *Analyzer) countsForChurn(file string) bool {
+	if len(a.ChurnIncludeGlobs) > 0 && !matchesAnyGlob(a.ChurnIncludeGlobs, file) {
+		return false
 	}
-	wg.Wait()
+	return !matchesAnyGlob(a.ChurnExcludeGlobs, file)
+}
 
-	return churn, nil
+// matchesAnyGlob reports whether a file path matches any of the path.Match patterns. A pattern matches the whole
+// path, its base name or the path of one of its parent directories, so "*.pb.go" matches generated files anywhere and "vendor"
+// or "/dist" match whole trees. Malformed patterns match nothing (Validate reports them).
+func matchesAnyGlob(patterns []string, file string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.Trim(pattern, "/")
+		if ok, _ := path.Match(pattern, path.Base(file)); ok {
+			return true
+		}
+		for p := file; p != "." && p != "/"; p = path.Dir(p) {
+			if ok, _ := path.Match(pattern, p); ok {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // GetChurnByDir returns the churn rate by directory, derived from churn by file.
@@ -184,10 +558,72 @@ func (a *Analyzer) GetChurnByDir(ctx context.Context, repo string) (map[string]i
 	if err != nil {
 		return nil, err
 	}
-	churnByDir := make(map[string]int)
+	return churnByDir(churnByFile), nil
+}
+
+// GetChurnByExtension returns the churn rate by file extension (".go", ".ts", ...), derived from churn by file.
+// Files without an extension are counted under "(none)".
+func (a *Analyzer) GetChurnByExtension(ctx context.Context, repo string) (map[string]int, error) {
+	churnByFile, err := a.GetChurnByFile(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	return churnByExtension(churnByFile), nil
+}
+
+// churnByDir groups churn by file into churn by directory.
+func churnByDir(churnByFile map[string]int) map[string]int {
+	churn := make(map[string]int)
+	for file, count := range churnByFile {
+		churn[filepath.Dir(file)] += count
+	}
+	return churn
+}
+
+// churnByExtension groups churn by file into churn by file extension.
+func churnByExtension(churnByFile map[string]int) map[string]int {
+	churn := make(map[string]int)
 	for file, count := range churnByFile {
-		dir := filepath.Dir(file)
-		churnByDir[dir] += count
+		ext := filepath.Ext(file)
+		if ext == "" {
+			ext = "(none)"
+		}
+		churn[ext] += count
+	}
+	return churn
+}
+
+// timezone returns Timezone, or UTC when unset.
+func (a *Analyzer) timezone() *time.Location {
+	if a.Timezone == nil {
+		return time.UTC
+	}
+	return a.Timezone
+}
+
+// TopChurn returns the n entries of a churn map with the highest counts, highest first, ties broken by path.
+// It returns every entry when n <= 0 or the map has fewer than n entries.
+func TopChurn(m map[string]int, n int) []FileChurn {
+	top := make([]FileChurn, 0, len(m))
+	for path, count := range m {
+		top = append(top, FileChurn{Path: path, Count: count})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Count != top[j].Count {
+			return top[i].Count > top[j].Count
+		}
+		return top[i].Path < top[j].Path
+	})
+	if n > 0 && len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
+
+// topChurnFiles returns TopChurnFiles, or 20 when unset.
+func (a *Analyzer) topChurnFiles() int {
+	if a.TopChurnFiles <= 0 {
+		return 20
 	}
-	return churnByDir, nil
+	return a.TopChurnFiles
 }
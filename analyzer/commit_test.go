@@ -0,0 +1,265 @@
+package analyzer
+
+import (
+	"context"
+	"maps"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// newTestAnalyzer returns a quiet Analyzer for the January 2024 period whose client talks to a test server
+// answering each request with the canned JSON of its path, relative to the REST base URL ("/graphql" for
+// GraphQL queries), or with a 404.
+func newTestAnalyzer(t *testing.T, routes map[string]string) *Analyzer {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/api/v3")
+		if r.URL.Path == "/api/graphql" {
+			path = "/graphql"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		// plenty left, so checkRateLimit never waits
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+		body, ok := routes[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			body = `{"message": "Not Found"}`
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := github.NewClient(srv.Client()).WithEnterpriseURLs(srv.URL, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewAnalyzerWithClient(client, "acme", "main", "ci.yml", testStart, testEnd, map[string][]string{"core": {"api"}})
+	a.Logger = nil
+	return a
+}
+
+func TestGetRevertRate(t *testing.T) {
+	tests := []struct {
+		name    string
+		commits string
+		want    float64
+	}{
+		{
+			name: "git and GitHub reverts",
+			commits: `[
+				{"sha": "c4", "commit": {"message": "Revert \"feat: add flag\"\n\nThis reverts commit 1a2b3c4d."}},
+				{"sha": "c3", "commit": {"message": "fix: handle nil config"}},
+				{"sha": "c2", "commit": {"message": "Add revert button to UI"}},
+				{"sha": "c1", "commit": {"message": "Roll back the cache\n\nThis reverts commit 0123456789abcdef0123456789abcdef01234567."}}
+			]`,
+			want: 50,
+		},
+		{
+			name: "false positives only",
+			commits: `[
+				{"sha": "c2", "commit": {"message": "Add revert button to UI"}},
+				{"sha": "c1", "commit": {"message": "Reverted the flag"}}
+			]`,
+			want: 0,
+		},
+		{
+			name:    "no commits",
+			commits: `[]`,
+			want:    0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAnalyzer(t, map[string]string{"/repos/acme/api/commits": tt.commits})
+			got, err := a.GetRevertRate(context.Background(), "api")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("GetRevertRate = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetConflictRateAndCount(t *testing.T) {
+	tests := []struct {
+		name      string
+		search    string
+		prs       map[int]string // full PR by number
+		wantRate  float64
+		wantCount int
+	}{
+		{
+			name: "two of four conflicting",
+			search: `{"total_count": 4, "items": [
+				{"number": 4, "state": "open", "created_at": "2024-01-20T10:00:00Z", "pull_request": {}},
+				{"number": 3, "state": "closed", "created_at": "2024-01-15T10:00:00Z", "pull_request": {}},
+				{"number": 2, "state": "closed", "created_at": "2024-01-10T10:00:00Z", "pull_request": {}},
+				{"number": 1, "state": "open", "created_at": "2024-01-05T10:00:00Z", "pull_request": {}}
+			]}`,
+			prs: map[int]string{
+				4: `{"number": 4, "state": "open", "mergeable_state": "dirty"}`,
+				3: `{"number": 3, "state": "closed", "mergeable_state": "clean"}`,
+				2: `{"number": 2, "state": "closed", "mergeable_state": "dirty"}`,
+				1: `{"number": 1, "state": "open", "mergeable_state": "blocked"}`,
+			},
+			wantRate:  50,
+			wantCount: 2,
+		},
+		{
+			name: "unknown state left out of the rate",
+			search: `{"total_count": 4, "items": [
+				{"number": 4, "state": "closed", "created_at": "2024-01-20T10:00:00Z", "pull_request": {}},
+				{"number": 3, "state": "closed", "created_at": "2024-01-15T10:00:00Z", "pull_request": {}},
+				{"number": 2, "state": "closed", "created_at": "2024-01-10T10:00:00Z", "pull_request": {}},
+				{"number": 1, "state": "closed", "created_at": "2024-01-05T10:00:00Z", "pull_request": {}}
+			]}`,
+			prs: map[int]string{
+				4: `{"number": 4, "state": "closed", "mergeable_state": "dirty"}`,
+				3: `{"number": 3, "state": "closed", "mergeable_state": "unknown"}`,
+				2: `{"number": 2, "state": "closed", "mergeable_state": "clean"}`,
+				1: `{"number": 1, "state": "closed"}`,
+			},
+			wantRate:  50,
+			wantCount: 1,
+		},
+		{
+			name:      "no PRs",
+			search:    `{"total_count": 0, "items": []}`,
+			wantRate:  0,
+			wantCount: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routes := map[string]string{"/search/issues": tt.search}
+			for number, pr := range tt.prs {
+				routes["/repos/acme/api/pulls/"+strconv.Itoa(number)] = pr
+			}
+			a := newTestAnalyzer(t, routes)
+			rate, count, err := a.GetConflictRateAndCount(context.Background(), "api")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rate != tt.wantRate || count != tt.wantCount {
+				t.Errorf("GetConflictRateAndCount = %v, %d, want %v, %d", rate, count, tt.wantRate, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestGetMainSizeCachedPerHead(t *testing.T) {
+	r := &fakeRepo{headSHA: "aaa", tree: []*github.TreeEntry{
+		{Type: github.String("blob"), Size: github.Int(100)},
+		{Type: github.String("tree")},
+		{Type: github.String("blob"), Size: github.Int(20)},
+	}}
+	p := &fakeProvider{repos: map[string]*fakeRepo{"api": r}}
+	a := newFakeAnalyzer(p, map[string][]string{"core": {"api"}})
+	ctx := context.Background()
+
+	check := func(step string, wantRequests, wantTrees int) {
+		t.Helper()
+		size, files, err := a.GetMainSize(ctx, "api")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size != 120 || files != 2 {
+			t.Errorf("%s: GetMainSize = %d bytes, %d files, want 120, 2", step, size, files)
+		}
+		if got := p.requests(); got != wantRequests {
+			t.Errorf("%s: %d requests so far, want %d", step, got, wantRequests)
+		}
+		if got := p.callsTo("GetTree"); got != wantTrees {
+			t.Errorf("%s: %d tree fetches so far, want %d", step, got, wantTrees)
+		}
+	}
+
+	check("first call", 2, 1)
+	check("second call", 2, 1)
+
+	a.memo.reset() // next run: HEAD is looked up again, the tree comes from the cache
+	check("next run, same HEAD", 3, 1)
+
+	a.memo.reset()
+	r.headSHA = "bbb"
+	check("next run, branch moved", 5, 2)
+}
+
+func TestGetCommitDistributionAttributeByEmail(t *testing.T) {
+	p := &fakeProvider{repos: map[string]*fakeRepo{"api": {commits: []*github.RepositoryCommit{
+		fakeCommit("c5", "alice", "alice@example.com", "fix: a", 5, 1),
+		fakeCommit("c4", "", "dev@example.com", "fix: b", 4, 1), // no linked account
+		fakeCommit("c3", "alice", "alice@work.example.com", "fix: c", 3, 1),
+		fakeCommit("c2", "", "dev@example.com", "fix: d", 2, 1),
+		fakeCommit("c1", "", "", "fix: e", 1, 1), // no account and no e-mail
+	}}}}
+
+	tests := []struct {
+		attributeByEmail bool
+		want             map[string]int
+	}{
+		{false, map[string]int{"alice": 2}},
+		{true, map[string]int{"alice": 2, "email:dev@example.com": 2}},
+	}
+	for _, tt := range tests {
+		a := newFakeAnalyzer(p, map[string][]string{"core": {"api"}})
+		a.AttributeByEmail = tt.attributeByEmail
+		got, err := a.GetCommitDistribution(context.Background(), "api")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !maps.Equal(got, tt.want) {
+			t.Errorf("AttributeByEmail=%v: GetCommitDistribution = %v, want %v", tt.attributeByEmail, got, tt.want)
+		}
+	}
+}
+
+func TestExcludeMergeCommits(t *testing.T) {
+	p := &fakeProvider{repos: map[string]*fakeRepo{"api": {commits: []*github.RepositoryCommit{
+		fakeCommit("c4", "bob", "bob@example.com", "Merge pull request #12 from acme/feature", 4, 2),
+		fakeCommit("c3", "alice", "alice@example.com", "Revert \"feat: flag\"\n\nThis reverts commit c1c1c1c1.", 3, 1),
+		fakeCommit("c2", "carol", "carol@example.com", "fix: typo", 2, 1),
+		fakeCommit("c1", "alice", "alice@example.com", "feat: flag", 1, 1),
+	}}}}
+
+	tests := []struct {
+		exclude          bool
+		wantContributors int
+		wantDist         map[string]int
+		wantRevertRate   float64
+	}{
+		{false, 3, map[string]int{"alice": 2, "bob": 1, "carol": 1}, 25},
+		{true, 2, map[string]int{"alice": 2, "carol": 1}, 100.0 / 3},
+	}
+	for _, tt := range tests {
+		a := newFakeAnalyzer(p, map[string][]string{"core": {"api"}})
+		a.ExcludeMergeCommits = tt.exclude
+		ctx := context.Background()
+
+		contributors, _, err := a.GetUniqueContributors(ctx, "api")
+		if err != nil {
+			t.Fatal(err)
+		}
+		dist, err := a.GetCommitDistribution(ctx, "api")
+		if err != nil {
+			t.Fatal(err)
+		}
+		revertRate, err := a.GetRevertRate(ctx, "api")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if contributors != tt.wantContributors || !maps.Equal(dist, tt.wantDist) || math.Abs(revertRate-tt.wantRevertRate) > 1e-9 {
+			t.Errorf("ExcludeMergeCommits=%v: contributors %d, distribution %v, revert rate %v, want %d, %v, %v",
+				tt.exclude, contributors, dist, revertRate, tt.wantContributors, tt.wantDist, tt.wantRevertRate)
+		}
+	}
+}
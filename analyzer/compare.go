@@ -0,0 +1,91 @@
+package analyzer
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// MetricDelta is the change of a scalar metric between two periods.
+type MetricDelta struct {
+	Current        float64 `json:"current"`
+	Previous       float64 `json:"previous"`
+	AbsoluteChange float64 `json:"absolute_change"`
+	// PercentChange is relative to Previous. When Previous is 0 it's 0 if Current is 0 too,
+	// and +Inf/-Inf otherwise; check with math.IsInf before encoding, as JSON has no infinity.
+	PercentChange float64 `json:"percent_change"`
+}
+
+// Compare runs Check for the current and the previous period ([2]time.Time{start, end}) and returns,
+// per repo, the change of every scalar metric keyed by its JSON name (e.g. "avg_merge_time_days").
+// Repos missing from one of the periods are compared against zero values.
+func (a *Analyzer) Compare(ctx context.Context, current, previous [2]time.Time) (map[string]map[string]MetricDelta, error) {
+	currentMetrics, err := a.withPeriod(current[0], current[1]).Check(ctx)
+	if err != nil {
+		return nil, err
+	}
+	previousMetrics, err := a.withPeriod(previous[0], previous[1]).Check(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	prevByRepo := make(map[string]RepoMetrics)
+	for _, m := range previousMetrics {
+		prevByRepo[m.Repo] = m
+	}
+
+	deltas := make(map[string]map[string]MetricDelta)
+	for _, m := range currentMetrics {
+		prev := scalarMetrics(prevByRepo[m.Repo])
+		repoDeltas := make(map[string]MetricDelta)
+		for name, cur := range scalarMetrics(m) {
+			repoDeltas[name] = newMetricDelta(cur, prev[name])
+		}
+		deltas[m.Repo] = repoDeltas
+	}
+	return deltas, nil
+}
+
+// withPeriod returns a copy of the analyzer for another period, sharing its client and caches.
+func (a *Analyzer) withPeriod(start, end time.Time) *Analyzer {
+	b := *a
+	b.StartDate = start
+	b.EndDate = end
+	return &b
+}
+
+func newMetricDelta(current, previous float64) MetricDelta {
+	d := MetricDelta{Current: current, Previous: previous, AbsoluteChange: current - previous}
+	switch {
+	case previous != 0:
+		d.PercentChange = d.AbsoluteChange / math.Abs(previous) * 100
+	case current > 0:
+		d.PercentChange = math.Inf(1)
+	case current < 0:
+		d.PercentChange = math.Inf(-1)
+	}
+	return d
+}
+
+// scalarMetrics returns the numeric fields of m keyed by their JSON name.
+func scalarMetrics(m RepoMetrics) map[string]float64 {
+	values := make(map[string]float64)
+	v := reflect.ValueOf(m)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		f := v.Field(i)
+		switch f.Kind() {
+		case reflect.Int, reflect.Int64:
+			values[name] = float64(f.Int())
+		case reflect.Float64:
+			values[name] = f.Float()
+		}
+	}
+	return values
+}
@@ -2,39 +2,78 @@ package analyzer
 
 import (
 	"context"
-
-	"github.com/google/go-github/v62/github"
+	"sort"
 )
 
 // GetUniqueContributors returns the number of unique contributors and their list for a repo in the period.
+// Commits whose author isn't linked to a GitHub account (nil Author, often the case for bots) aren't counted.
 func (a *Analyzer) GetUniqueContributors(ctx context.Context, repo string) (int, []string, error) {
-	commitOpts := &github.CommitsListOptions{
-		Since:       a.StartDate,
-		Until:       a.EndDate,
-		ListOptions: github.ListOptions{PerPage: 100},
+	commits, err := a.getCommits(ctx, repo)
+	if err != nil {
+		return 0, nil, err
 	}
 
 	unique := make(map[string]struct{})
-
-	for {
-		commits, resp, err := a.client.Repositories.ListCommits(ctx, a.Owner, repo, commitOpts)
-		if err != nil {
-			return 0, nil, err
+	for _, c := range commits {
+		if c.Author != nil && c.Author.Login != nil && !a.excludedAuthor(*c.Author.Login) {
+			unique[*c.Author.Login] = struct{}{}
 		}
+	}
 
-		for _, c := range commits {
-			if c.Author != nil && c.Author.Login != nil {
-				unique[*c.Author.Login] = struct{}{}
-			}
-		}
+	usernames := getUsernames(unique)
+	return len(unique), usernames, nil
+}
+
+// GetBusFactor returns the minimum number of top contributors who together made more than
+// BusFactorThreshold (50% by default) of the commits in the period, from GetCommitDistribution.
+// 1 means a single person made most of the changes, the highest risk; 0 means there were no commits.
+func (a *Analyzer) GetBusFactor(ctx context.Context, repo string) (int, error) {
+	dist, err := a.GetCommitDistribution(ctx, repo)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	counts := make([]int, 0, len(dist))
+	for _, n := range dist {
+		counts = append(counts, n)
+		total += n
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(counts)))
 
-		if resp.NextPage == 0 {
-			break
+	threshold := a.BusFactorThreshold
+	if threshold <= 0 || threshold >= 1 {
+		threshold = 0.5
+	}
+	sum := 0
+	for i, n := range counts {
+		sum += n
+		if float64(sum) > threshold*float64(total) {
+			return i + 1, nil
 		}
-		commitOpts.Page = resp.NextPage
-		a.checkRateLimit(resp)
 	}
+	return len(counts), nil
+}
 
-	usernames := getUsernames(unique)
-	return len(unique), usernames, nil
+// GetUnlinkedCommits returns the number of commits in the period whose git author isn't linked to a GitHub
+// account (no GitHub Author, but a git identity), along with their count per git author e-mail.
+// These commits are invisible to GetUniqueContributors and GetCommitDistribution.
+func (a *Analyzer) GetUnlinkedCommits(ctx context.Context, repo string) (int, map[string]int, error) {
+	commits, err := a.getCommits(ctx, repo)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	count := 0
+	byEmail := make(map[string]int)
+	for _, c := range commits {
+		if c.Author != nil || c.Commit == nil || c.Commit.Author == nil {
+			continue
+		}
+		count++
+		if email := c.Commit.Author.GetEmail(); email != "" {
+			byEmail[email]++
+		}
+	}
+	return count, byEmail, nil
 }
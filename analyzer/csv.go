@@ -0,0 +1,75 @@
+package analyzer
+
+import (
+	"encoding/csv"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ExportCSV exports the metrics to a CSV file with one row per repo and returns its path (see outputPath).
+// Columns are the scalar fields of RepoMetrics, named after their JSON keys; maps and lists
+// (churn, distributions, errors...) are left out, the JSON export has them.
+func (a *Analyzer) ExportCSV(metrics []RepoMetrics, filename string) (string, error) {
+	path, err := a.outputPath(filename, ".csv")
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	fields := csvFields()
+	w := csv.NewWriter(f)
+	header := make([]string, len(fields))
+	for i, field := range fields {
+		header[i], _, _ = strings.Cut(field.Tag.Get("json"), ",")
+	}
+	if err := w.Write(header); err != nil {
+		return "", err
+	}
+
+	for _, m := range metrics {
+		v := reflect.ValueOf(m)
+		row := make([]string, len(fields))
+		for i, field := range fields {
+			row[i] = csvValue(v.FieldByIndex(field.Index))
+		}
+		if err := w.Write(row); err != nil {
+			return "", err
+		}
+	}
+
+	w.Flush()
+	return path, w.Error()
+}
+
+// csvFields returns the scalar fields of RepoMetrics, in declaration order.
+func csvFields() []reflect.StructField {
+	var fields []reflect.StructField
+	t := reflect.TypeOf(RepoMetrics{})
+	for i := 0; i < t.NumField(); i++ {
+		switch t.Field(i).Type.Kind() {
+		case reflect.String, reflect.Bool, reflect.Int, reflect.Int64, reflect.Float64:
+			fields = append(fields, t.Field(i))
+		}
+	}
+	return fields
+}
+
+// csvValue formats a scalar field of RepoMetrics as a CSV cell.
+func csvValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', 2, 64)
+	}
+	return v.String()
+}
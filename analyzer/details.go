@@ -0,0 +1,153 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RepoDetails holds the records behind the aggregates of a repo, so any of them can be checked by hand.
+// It's collected with CollectDetails and written by ExportDetails.
+type RepoDetails struct {
+	PullRequests []PRDetail          `json:"pull_requests"`
+	Commits      []CommitDetail      `json:"commits"`
+	WorkflowRuns []WorkflowRunDetail `json:"workflow_runs"`
+}
+
+// PRDetail is a PR of the period, as seen by the PR-based metrics.
+type PRDetail struct {
+	Number         int        `json:"number"`
+	Author         string     `json:"author"`
+	CreatedAt      time.Time  `json:"created_at"`
+	MergedAt       *time.Time `json:"merged_at,omitempty"`
+	MergeTimeHours float64    `json:"merge_time_hours,omitempty"` // from creation, 0 for unmerged PRs
+	Reviewers      []string   `json:"reviewers"`
+}
+
+// CommitDetail is a commit of the period, as seen by the commit-based metrics.
+type CommitDetail struct {
+	SHA    string    `json:"sha"`
+	Author string    `json:"author"` // GitHub login, or the git e-mail for commits not linked to an account
+	Date   time.Time `json:"date"`
+	Revert bool      `json:"revert"`
+}
+
+// WorkflowRunDetail is a run of the workflow in the period, as seen by the action metrics.
+type WorkflowRunDetail struct {
+	ID              int64     `json:"id"`
+	Conclusion      string    `json:"conclusion"`
+	Attempt         int       `json:"attempt"`
+	HeadSHA         string    `json:"head_sha"`
+	CreatedAt       time.Time `json:"created_at"`
+	DurationSeconds int       `json:"duration_seconds"`
+}
+
+// GetRepoDetails returns the PRs, commits and workflow runs of the period behind the metrics of a repo.
+// It reads the listings shared with the metrics, plus the reviews of each PR (shared with the review metrics).
+// PRs whose reviews can't be fetched are listed without reviewers.
+func (a *Analyzer) GetRepoDetails(ctx context.Context, repo string) (*RepoDetails, error) {
+	d := &RepoDetails{}
+
+	prs, err := a.listPullRequests(ctx, repo, "all")
+	if err != nil {
+		return nil, err
+	}
+	var mu sync.Mutex
+	g, gctx := a.innerGroup(ctx)
+	for _, pr := range prs {
+		if gctx.Err() != nil {
+			break
+		}
+		g.Go(func() error {
+			detail := PRDetail{Number: pr.GetNumber(), Author: pr.GetUser().GetLogin(), CreatedAt: pr.GetCreatedAt().Time}
+			if pr.MergedAt != nil {
+				merged := pr.MergedAt.Time
+				detail.MergedAt = &merged
+				detail.MergeTimeHours = merged.Sub(detail.CreatedAt).Hours()
+			}
+			reviews, err := a.listReviews(gctx, repo, pr.GetNumber())
+			reviewers := make(map[string]struct{})
+			for _, r := range reviews {
+				if login := r.GetUser().GetLogin(); login != "" {
+					reviewers[login] = struct{}{}
+				}
+			}
+			detail.Reviewers = getUsernames(reviewers)
+			sort.Strings(detail.Reviewers)
+			mu.Lock()
+			d.PullRequests = append(d.PullRequests, detail)
+			mu.Unlock()
+			return hardError(err)
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	sort.Slice(d.PullRequests, func(i, j int) bool { return d.PullRequests[i].Number < d.PullRequests[j].Number })
+
+	commits, err := a.getCommits(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range commits {
+		author := c.GetAuthor().GetLogin()
+		if author == "" {
+			author = c.GetCommit().GetAuthor().GetEmail()
+		}
+		d.Commits = append(d.Commits, CommitDetail{
+			SHA:    c.GetSHA(),
+			Author: author,
+			Date:   c.GetCommit().GetAuthor().GetDate().Time,
+			Revert: isRevertMessage(c.GetCommit().GetMessage()),
+		})
+	}
+
+	runs, err := a.listWorkflowRuns(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	for _, run := range runs {
+		detail := WorkflowRunDetail{
+			ID:         run.GetID(),
+			Conclusion: run.GetConclusion(),
+			Attempt:    run.GetRunAttempt(),
+			HeadSHA:    run.GetHeadSHA(),
+			CreatedAt:  run.GetCreatedAt().Time,
+		}
+		if run.GetStatus() == "completed" && run.RunStartedAt != nil && run.UpdatedAt != nil {
+			detail.DurationSeconds = int(run.UpdatedAt.Sub(run.RunStartedAt.Time).Seconds())
+		}
+		d.WorkflowRuns = append(d.WorkflowRuns, detail)
+	}
+
+	return d, nil
+}
+
+// ExportDetails exports the RepoDetails of the metrics collected with CollectDetails to a JSON object keyed
+// by repo, apart from the main report so it stays lean, and returns its path. An empty filename is named
+// after the period ("details-2024-06-01_2024-06-30.json"); see outputPath. Repos without details are left out.
+func (a *Analyzer) ExportDetails(metrics []RepoMetrics, filename string) (string, error) {
+	if filename == "" {
+		filename = fmt.Sprintf("details-%s_%s.json", a.StartDate.Format("2006-01-02"), a.EndDate.Format("2006-01-02"))
+	}
+	path, err := a.outputPath(filename, ".json")
+	if err != nil {
+		return "", err
+	}
+
+	details := make(map[string]*RepoDetails)
+	for _, m := range metrics {
+		if m.Details != nil {
+			details[m.Repo] = m.Details
+		}
+	}
+	jsonData, err := json.MarshalIndent(details, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, jsonData, 0644)
+}
@@ -0,0 +1,82 @@
+package analyzer
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// DiscoverOptions controls how DiscoverRepos groups the repos of an organization into areas.
+// Topic rules are tried first, then name prefixes (longest first); repos matching no rule go to DefaultArea.
+type DiscoverOptions struct {
+	IncludeForks bool              // Also return forked repos
+	TopicAreas   map[string]string // Key: repo topic, Value: area
+	PrefixAreas  map[string]string // Key: repo name prefix (e.g. "web-"), Value: area
+	DefaultArea  string            // Area of repos matching no rule; defaults to "Uncategorized"
+}
+
+// DiscoverRepos lists the non-archived repos of an organization and groups them into areas, in the
+// format of Projects. Repos of orgs other than Owner are qualified as "org/repo".
+func (a *Analyzer) DiscoverRepos(ctx context.Context, org string, opts DiscoverOptions) (map[string][]string, error) {
+	defaultArea := opts.DefaultArea
+	if defaultArea == "" {
+		defaultArea = UncategorizedArea
+	}
+	prefixes := make([]string, 0, len(opts.PrefixAreas))
+	for prefix := range opts.PrefixAreas {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+
+	projects := make(map[string][]string)
+	listOpts := &github.RepositoryListByOrgOptions{Type: "all", ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		repos, resp, err := doRequest(ctx, a, func() ([]*github.Repository, *github.Response, error) {
+			return a.provider.ListRepositoriesByOrg(ctx, org, listOpts)
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range repos {
+			if r.GetArchived() || (r.GetFork() && !opts.IncludeForks) {
+				continue
+			}
+			name := r.GetName()
+			if !strings.EqualFold(org, a.Owner) {
+				name = org + "/" + name
+			}
+			area := discoverArea(r, opts, prefixes)
+			if area == "" {
+				area = defaultArea
+			}
+			projects[area] = append(projects[area], name)
+		}
+		if a.lastPage(resp, listOpts.Page) {
+			break
+		}
+		listOpts.Page = resp.NextPage
+	}
+
+	for _, repos := range projects {
+		sort.Strings(repos)
+	}
+	return projects, nil
+}
+
+// discoverArea returns the area of a repo by the rules of opts, or "" when no rule matches.
+// prefixes are the keys of opts.PrefixAreas, longest first.
+func discoverArea(r *github.Repository, opts DiscoverOptions, prefixes []string) string {
+	for _, topic := range r.Topics {
+		if area, ok := opts.TopicAreas[topic]; ok {
+			return area
+		}
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(r.GetName(), prefix) {
+			return opts.PrefixAreas[prefix]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,39 @@
+package analyzer
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// Errors returned by the package, wrapped with the repo (and the underlying API error, when there's one),
+// so callers can tell them apart with errors.Is.
+var (
+	ErrWorkflowNotFound = errors.New("workflow not found")
+	ErrRepoNotFound     = errors.New("repository not found")
+	ErrUnauthorizedRepo = errors.New("repository not accessible with this token")
+	ErrTagNotFound      = errors.New("tag not found")
+	ErrRepoAborted      = errors.New("repository aborted after repeated API failures")
+	ErrRateLimited      = errors.New("rate limited")
+	ErrEmptyRepo        = errors.New("repository is empty")
+)
+
+// classifyError wraps a REST API error with the sentinel error matching its class, if any.
+// The API error stays in the chain, so errors.As still finds a *github.ErrorResponse.
+func classifyError(err error) error {
+	var rateErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	var errResp *github.ErrorResponse
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &rateErr), errors.As(err, &abuseErr):
+		return fmt.Errorf("%w: %w", ErrRateLimited, err)
+	case errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusConflict:
+		// GitHub answers 409 Conflict to commit and git data requests on a repo without commits
+		return fmt.Errorf("%w: %w", ErrEmptyRepo, err)
+	}
+	return err
+}
@@ -1,19 +1,94 @@
 package analyzer
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/go-github/v62/github"
+	"golang.org/x/sync/errgroup"
 )
 
-// checkRateLimit checks the rate limit and sleeps if necessary.
+// checkRateLimit checks the rate limit and sleeps if necessary. It's called with every response,
+// which also feeds the RateLimitStats of the current run.
+// Search requests have their own, much smaller budget (30 requests per minute), identified by
+// the limit of the response; when it's almost exhausted we wait for it to reset.
 func (a *Analyzer) checkRateLimit(resp *github.Response) {
-	if resp.Rate.Remaining < 100 {
-		time.Sleep(5 * time.Second) // Adjustable
+	if resp == nil {
+		return
 	}
+	search := resp.Rate.Limit > 0 && resp.Rate.Limit <= searchRateLimit
+	a.rateStats.record(resp, search)
+
+	var wait time.Duration
+	if search {
+		if resp.Rate.Remaining < 2 {
+			wait = time.Until(resp.Rate.Reset.Time)
+		}
+	} else if resp.Rate.Remaining < 100 {
+		wait = 5 * time.Second // Adjustable
+	}
+	if wait > 0 {
+		a.rateStats.slept(wait)
+		time.Sleep(wait)
+	}
+}
+
+// innerConcurrency returns the number of per-item requests (one per PR, commit, issue...) a metric
+// may have in flight, i.e. InnerConcurrency, never less than 1.
+func (a *Analyzer) innerConcurrency() int {
+	if a.InnerConcurrency < 1 {
+		return 1
+	}
+	return a.InnerConcurrency
+}
+
+// innerGroup returns the group a metric fans its per-item requests out to, running up to innerConcurrency
+// of them at once. Its context is canceled by the first item returning an error, see hardError.
+func (a *Analyzer) innerGroup(ctx context.Context) (*errgroup.Group, context.Context) {
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(a.innerConcurrency())
+	return g, gctx
+}
+
+// hardError returns err when it must abort a whole metric: the run was canceled or ran out of requests,
+// or the repo was aborted (see FailFastThreshold).
+// Other errors only affect the item that got them, which is skipped, so hardError returns nil for them.
+func hardError(err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, ErrRequestBudgetExceeded) || errors.Is(err, ErrRepoAborted) {
+		return err
+	}
+	return nil
+}
+
+// lastPage reports whether pagination must stop after the current page (ListOptions.Page, 0 for the first one):
+// either there's no next page or MaxPages pages were fetched.
+func (a *Analyzer) lastPage(resp *github.Response, page int) bool {
+	if page < 1 {
+		page = 1
+	}
+	return resp.NextPage == 0 || (a.MaxPages > 0 && page >= a.MaxPages)
+}
+
+// searchRateLimit is the per-minute rate limit of the Search API for authenticated requests.
+const searchRateLimit = 30
+
+// splitRepo returns the owner and name of a repo from Projects, which may be qualified as "owner/repo"
+// to analyze repos of several owners in one run. Unqualified repos belong to a.Owner.
+func (a *Analyzer) splitRepo(repo string) (owner, name string) {
+	if owner, name, ok := strings.Cut(repo, "/"); ok {
+		return owner, name
+	}
+	return a.Owner, repo
 }
 
 // getUsernames converts a map to a slice of usernames.
@@ -25,6 +100,118 @@ func getUsernames(unique map[string]struct{}) []string {
 	return list
 }
 
+// inPeriod reports whether t falls in the analyzed period; nil never does.
+func (a *Analyzer) inPeriod(t *github.Timestamp) bool {
+	return t != nil && t.After(a.StartDate) && t.Before(a.EndDate)
+}
+
+// prInPeriod reports whether a PR falls in the period according to basis, usually TimeBasis.
+func (a *Analyzer) prInPeriod(pr *github.PullRequest, basis TimeBasis) bool {
+	switch basis {
+	case TimeBasisUpdated:
+		return a.inPeriod(pr.UpdatedAt)
+	case TimeBasisMerged:
+		return a.inPeriod(pr.MergedAt)
+	case TimeBasisClosed:
+		return a.inPeriod(pr.ClosedAt)
+	}
+	return a.inPeriod(pr.CreatedAt)
+}
+
+// issueInPeriod reports whether an issue falls in the period according to TimeBasis.
+// Issues aren't merged, so TimeBasisMerged places them by closing time.
+func (a *Analyzer) issueInPeriod(i *github.Issue) bool {
+	switch a.TimeBasis {
+	case TimeBasisUpdated:
+		return a.inPeriod(i.UpdatedAt)
+	case TimeBasisMerged, TimeBasisClosed:
+		return a.inPeriod(i.ClosedAt)
+	}
+	return a.inPeriod(i.CreatedAt)
+}
+
+// sortMetrics returns the metrics ordered by area, then repo (and owner), whatever order the repos completed in,
+// so reports and logs come out the same on every run. Duplicate entries of a repo in the same area are merged
+// into one, keeping the entry with the fewest failed metrics (the first one on a tie). The input isn't modified.
+func sortMetrics(metrics []RepoMetrics) []RepoMetrics {
+	sorted := make([]RepoMetrics, 0, len(metrics))
+	index := make(map[[3]string]int) // area, owner, repo -> position in sorted
+	for _, m := range metrics {
+		key := [3]string{m.Area, m.Owner, m.Repo}
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(sorted)
+			sorted = append(sorted, m)
+			continue
+		}
+		if len(m.Errors) < len(sorted[i].Errors) {
+			sorted[i] = m
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Area != sorted[j].Area {
+			return sorted[i].Area < sorted[j].Area
+		}
+		if sorted[i].Repo != sorted[j].Repo {
+			return sorted[i].Repo < sorted[j].Repo
+		}
+		return sorted[i].Owner < sorted[j].Owner
+	})
+	return sorted
+}
+
+// percentile returns the p-th percentile (0-100) of sorted, non-empty values,
+// interpolating linearly between the two closest ranks.
+func percentile(sorted []float64, p float64) float64 {
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(rank)
+	if lower >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	frac := rank - float64(lower)
+	return sorted[lower] + frac*(sorted[lower+1]-sorted[lower])
+}
+
+// hasLabel reports whether the issue carries any of the labels, ignoring case.
+func hasLabel(issue *github.Issue, labels []string) bool {
+	for _, l := range issue.Labels {
+		for _, want := range labels {
+			if strings.EqualFold(l.GetName(), want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var (
+	// revertSubject matches the subject generated by `git revert` and GitHub's revert button.
+	revertSubject = regexp.MustCompile(`^Revert "`)
+	// revertBody matches the body line generated by `git revert`.
+	revertBody = regexp.MustCompile(`(?m)^This reverts commit [0-9a-f]{7,40}\.?\s*$`)
+	// revertPRTitle matches PR titles starting with the word "Revert".
+	revertPRTitle = regexp.MustCompile(`(?i)^revert\b`)
+	// conventionalSubject matches a Conventional Commits subject, `type(scope)!: description`.
+	conventionalSubject = regexp.MustCompile(`^([a-zA-Z]+)(\([^)]*\))?!?: \S`)
+)
+
+// isRevertMessage reports whether a commit message belongs to a revert commit.
+func isRevertMessage(msg string) bool {
+	return revertSubject.MatchString(msg) || revertBody.MatchString(msg)
+}
+
+// commitType returns the Conventional Commits type of a commit message in lower case, "revert" for
+// revert commits generated by git or GitHub, or "other" when the message follows neither convention.
+func commitType(msg string) string {
+	if m := conventionalSubject.FindStringSubmatch(msg); m != nil {
+		return strings.ToLower(m[1])
+	}
+	if isRevertMessage(msg) {
+		return "revert"
+	}
+	return "other"
+}
+
 // FormatSecondsToHMS transforms seconds into hh:mm:ss format.
 func (a *Analyzer) FormatSecondsToHMS(seconds int) string {
 	h := seconds / 3600
@@ -33,11 +220,64 @@ func (a *Analyzer) FormatSecondsToHMS(seconds int) string {
 	return fmt.Sprintf("%02d:%02d:%02d", h, m, s)
 }
 
-// Export exports the metrics to a JSON file.
-func (a *Analyzer) Export(metrics []RepoMetrics, filename string) error {
+// Export exports the metrics to a JSON file and returns its path (see outputPath).
+func (a *Analyzer) Export(metrics []RepoMetrics, filename string) (string, error) {
+	path, err := a.outputPath(filename, ".json")
+	if err != nil {
+		return "", err
+	}
 	jsonData, err := json.MarshalIndent(metrics, "", "  ")
 	if err != nil {
-		return err
+		return "", err
+	}
+	return path, os.WriteFile(path, jsonData, 0644)
+}
+
+// ExportNDJSON writes the metrics as JSON Lines: one compact JSON object per repo, each followed by a newline.
+func (a *Analyzer) ExportNDJSON(metrics []RepoMetrics, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	for i := range metrics {
+		if err := enc.Encode(&metrics[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportV2 exports the metrics to a JSON file wrapped in a versioned Report envelope, so consumers can
+// branch on schema_version and tell which owner and period a cached report covers. It returns the path of the file.
+func (a *Analyzer) ExportV2(metrics []RepoMetrics, filename string) (string, error) {
+	path, err := a.outputPath(filename, ".json")
+	if err != nil {
+		return "", err
+	}
+	report := Report{
+		SchemaVersion: SchemaVersion,
+		GeneratedAt:   time.Now().UTC(),
+		ToolVersion:   Version,
+		Owner:         a.Owner,
+		Period:        ReportPeriod{From: a.StartDate, To: a.EndDate},
+		Repos:         metrics,
+	}
+	jsonData, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, jsonData, 0644)
+}
+
+// outputPath returns the path an export writes to. An empty filename is replaced by one named
+// after the period ("metrics-2024-06-01_2024-06-30" plus ext), so scheduled runs don't overwrite
+// each other's reports. Relative paths are placed under OutputDir, which is created if missing.
+func (a *Analyzer) outputPath(filename, ext string) (string, error) {
+	if filename == "" {
+		filename = fmt.Sprintf("metrics-%s_%s%s", a.StartDate.Format("2006-01-02"), a.EndDate.Format("2006-01-02"), ext)
+	}
+	if a.OutputDir != "" && !filepath.IsAbs(filename) {
+		filename = filepath.Join(a.OutputDir, filename)
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return "", err
 	}
-	return os.WriteFile(filename, jsonData, 0644)
+	return filename, nil
 }
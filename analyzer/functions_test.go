@@ -0,0 +1,98 @@
+package analyzer
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestRevertDetection(t *testing.T) {
+	tests := []struct {
+		msg        string
+		wantRevert bool
+		wantType   string
+	}{
+		{`Revert "feat: add flag"`, true, "revert"},
+		{"Revert \"fix(api): retry on 502\"\n\nThis reverts commit 1a2b3c4d5e6f.", true, "revert"},
+		{"Roll back the cache\n\nThis reverts commit 0123456789abcdef0123456789abcdef01234567.", true, "revert"},
+		{"revert: drop the flag", false, "revert"}, // conventional type, not a git revert
+		{"Reverted the flag", false, "other"},
+		{"Add revert button to UI", false, "other"},
+		{"fix: revert button misaligned", false, "fix"},
+		{"Fix the revert\n\nThis reverts commit later.", false, "other"},
+		{"feat(ui)!: new layout", false, "feat"},
+		{"Chore: bump deps", false, "chore"},
+		{"", false, "other"},
+	}
+	for _, tt := range tests {
+		if got := isRevertMessage(tt.msg); got != tt.wantRevert {
+			t.Errorf("isRevertMessage(%q) = %v, want %v", tt.msg, got, tt.wantRevert)
+		}
+		if got := commitType(tt.msg); got != tt.wantType {
+			t.Errorf("commitType(%q) = %q, want %q", tt.msg, got, tt.wantType)
+		}
+	}
+}
+
+func TestRevertPRTitle(t *testing.T) {
+	tests := []struct {
+		title string
+		want  bool
+	}{
+		{`Revert "Add retries"`, true},
+		{"revert: drop the flag", true},
+		{"Revert the cache change", true},
+		{"Reverted the flag", false},
+		{"Add revert button", false},
+	}
+	for _, tt := range tests {
+		if got := revertPRTitle.MatchString(tt.title); got != tt.want {
+			t.Errorf("revertPRTitle.MatchString(%q) = %v, want %v", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestSortMetrics(t *testing.T) {
+	failed := map[string]string{"ConflictRateAndCount": "timeout"}
+	input := []RepoMetrics{
+		{Area: "payments", Owner: "acme", Repo: "ledger"},
+		{Area: "core", Owner: "acme", Repo: "web", Errors: failed},
+		{Area: "core", Owner: "acme", Repo: "api"},
+		{Area: "core", Owner: "acme", Repo: "web"}, // duplicate without errors: kept
+		{Area: "core", Owner: "other", Repo: "api"},
+		{Area: "payments", Owner: "acme", Repo: "api"}, // same repo name in another area
+		{Area: "Uncategorized", Owner: "acme", Repo: "tools"},
+	}
+	want := []string{
+		"Uncategorized/acme/tools",
+		"core/acme/api",
+		"core/other/api",
+		"core/acme/web",
+		"payments/acme/api",
+		"payments/acme/ledger",
+	}
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	for round := 0; round < 20; round++ {
+		shuffled := slices.Clone(input)
+		rng.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+		before := slices.Clone(shuffled)
+
+		sorted := sortMetrics(shuffled)
+		var got []string
+		for _, m := range sorted {
+			got = append(got, m.Area+"/"+m.Owner+"/"+m.Repo)
+			if m.Repo == "web" && len(m.Errors) != 0 {
+				t.Errorf("round %d: kept the web entry with errors", round)
+			}
+		}
+		if !slices.Equal(got, want) {
+			t.Fatalf("round %d: sortMetrics order = %v, want %v", round, got, want)
+		}
+		for i := range before {
+			if before[i].Repo != shuffled[i].Repo || before[i].Area != shuffled[i].Area {
+				t.Fatalf("round %d: sortMetrics modified its input", round)
+			}
+		}
+	}
+}
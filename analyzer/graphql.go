@@ -0,0 +1,293 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/shurcooL/githubv4"
+)
+
+// gqlPullRequest is a PR as fetched by the GraphQL API, with the data that the REST API
+// only exposes through one extra request per PR.
+type gqlPullRequest struct {
+	Number    int
+	CreatedAt githubv4.DateTime
+	UpdatedAt githubv4.DateTime
+	MergedAt  *githubv4.DateTime
+	ClosedAt  *githubv4.DateTime
+	IsDraft   bool
+	Mergeable githubv4.MergeableState
+	Labels    struct {
+		Nodes []struct {
+			Name string
+		}
+	} `graphql:"labels(first: 20)"`
+	Reviews struct {
+		Nodes []gqlReview
+	} `graphql:"reviews(first: 100)"`
+	ReviewThreads struct {
+		TotalCount int
+		Nodes      []struct {
+			IsResolved bool
+			Comments   struct {
+				TotalCount int
+			}
+		}
+	} `graphql:"reviewThreads(first: 100)"`
+}
+
+// gqlReview is a review of a gqlPullRequest.
+type gqlReview struct {
+	Author struct {
+		Login string
+	}
+	State       githubv4.PullRequestReviewState
+	SubmittedAt *githubv4.DateTime
+}
+
+// gqlPageSize is the number of PRs fetched per GraphQL query.
+const gqlPageSize = 50
+
+// newGraphQLClient creates a GraphQL client sharing the HTTP client (and authentication) of the REST client.
+func newGraphQLClient(client *github.Client) *githubv4.Client {
+	httpClient := client.Client()
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	base := client.BaseURL.String()
+	if client.BaseURL.Host == "api.github.com" {
+		return githubv4.NewClient(httpClient)
+	}
+	// GitHub Enterprise serves REST under /api/v3/ and GraphQL under /api/graphql
+	return githubv4.NewEnterpriseClient(strings.TrimSuffix(base, "v3/")+"graphql", httpClient)
+}
+
+// listPullRequestsGraphQL returns the PRs in the period (see TimeBasis), with their reviews and mergeable state,
+// in batches of gqlPageSize PRs per request, shared by the metrics of a run. Only PRs targeting BaseBranchFilter
+// are listed, and PRLabelFilter applies as for the REST listing; with ExcludeDrafts, PRs still in draft are left out.
+// Queries go through doRequest like REST requests (see gqlQuery).
+func (a *Analyzer) listPullRequestsGraphQL(ctx context.Context, repo string) ([]gqlPullRequest, error) {
+	owner, name := a.splitRepo(repo)
+	return memoize(a.memo, a.memoKey("gql-prs", repo), func() ([]gqlPullRequest, error) {
+		var q struct {
+			Repository struct {
+				PullRequests struct {
+					Nodes    []gqlPullRequest
+					PageInfo struct {
+						EndCursor   githubv4.String
+						HasNextPage bool
+					}
+				} `graphql:"pullRequests(first: $pageSize, after: $cursor, orderBy: $orderBy, baseRefName: $baseRefName)"`
+			} `graphql:"repository(owner: $owner, name: $name)"`
+			RateLimit gqlRateLimit
+		}
+		variables := map[string]interface{}{
+			"owner":       githubv4.String(owner),
+			"name":        githubv4.String(name),
+			"pageSize":    githubv4.Int(gqlPageSize),
+			"cursor":      (*githubv4.String)(nil),
+			"orderBy":     githubv4.IssueOrder{Field: githubv4.IssueOrderFieldCreatedAt, Direction: githubv4.OrderDirectionDesc},
+			"baseRefName": (*githubv4.String)(nil),
+		}
+		if a.BaseBranchFilter != "" {
+			variables["baseRefName"] = githubv4.NewString(githubv4.String(a.BaseBranchFilter))
+		}
+		if a.TimeBasis != TimeBasisCreated {
+			// merging or closing a PR updates it, so update order bounds the other bases
+			variables["orderBy"] = githubv4.IssueOrder{Field: githubv4.IssueOrderFieldUpdatedAt, Direction: githubv4.OrderDirectionDesc}
+		}
+
+		var allPRs []gqlPullRequest
+		for page := 1; ; page++ {
+			if err := a.gqlQuery(ctx, &q, &q.RateLimit, variables); err != nil {
+				return nil, err
+			}
+			for _, pr := range q.Repository.PullRequests.Nodes {
+				sortedAt := pr.CreatedAt
+				if a.TimeBasis != TimeBasisCreated {
+					sortedAt = pr.UpdatedAt
+				}
+				if sortedAt.Before(a.StartDate) {
+					// sorted newest first: nothing older is in the period
+					return allPRs, nil
+				}
+				if a.gqlPRInPeriod(pr) && a.keepGQLPR(pr) {
+					allPRs = append(allPRs, pr)
+				}
+			}
+			if !q.Repository.PullRequests.PageInfo.HasNextPage || (a.MaxPages > 0 && page >= a.MaxPages) {
+				break
+			}
+			variables["cursor"] = githubv4.NewString(q.Repository.PullRequests.PageInfo.EndCursor)
+		}
+		return allPRs, nil
+	})
+}
+
+// gqlRateLimit is the rate limit of the GraphQL API, queried along with the data of every query.
+type gqlRateLimit struct {
+	Limit     int
+	Remaining int
+	ResetAt   githubv4.DateTime
+}
+
+// gqlQuery sends a GraphQL query through doRequest, so it's counted, throttled and retried like a REST request
+// and feeds the repo's breaker. GraphQL has no REST-style response, so the rate limit queried into rate stands
+// for it in checkRateLimit (GraphQL has its own budget of points, reported in RateLimitStats like the REST one),
+// and a secondary rate limit, which githubv4 only reports in the error message, is retried as an
+// AbuseRateLimitError.
+func (a *Analyzer) gqlQuery(ctx context.Context, q interface{}, rate *gqlRateLimit, variables map[string]interface{}) error {
+	_, _, err := doRequest(ctx, a, func() (struct{}, *github.Response, error) {
+		if err := a.gql.Query(ctx, q, variables); err != nil {
+			if strings.Contains(err.Error(), "secondary rate limit") {
+				return struct{}{}, nil, &github.AbuseRateLimitError{Message: err.Error()}
+			}
+			return struct{}{}, nil, err
+		}
+		return struct{}{}, &github.Response{
+			Response: &http.Response{StatusCode: http.StatusOK},
+			Rate:     github.Rate{Limit: rate.Limit, Remaining: rate.Remaining, Reset: github.Timestamp{Time: rate.ResetAt.Time}},
+		}, nil
+	})
+	return err
+}
+
+// keepGQLPR is keepPR for a gqlPullRequest.
+func (a *Analyzer) keepGQLPR(pr gqlPullRequest) bool {
+	if a.ExcludeDrafts && pr.IsDraft {
+		return false
+	}
+	if len(a.PRLabelFilter) == 0 {
+		return true
+	}
+	labels := make([]*github.Label, 0, len(pr.Labels.Nodes))
+	for _, l := range pr.Labels.Nodes {
+		labels = append(labels, &github.Label{Name: github.String(l.Name)})
+	}
+	return hasLabel(&github.Issue{Labels: labels}, a.PRLabelFilter)
+}
+
+// gqlPRInPeriod is prInPeriod for a gqlPullRequest.
+func (a *Analyzer) gqlPRInPeriod(pr gqlPullRequest) bool {
+	pick := &pr.CreatedAt
+	switch a.TimeBasis {
+	case TimeBasisUpdated:
+		pick = &pr.UpdatedAt
+	case TimeBasisMerged:
+		pick = pr.MergedAt
+	case TimeBasisClosed:
+		pick = pr.ClosedAt
+	}
+	return pick != nil && pick.After(a.StartDate) && pick.Before(a.EndDate)
+}
+
+// conflictRateAndCountGraphQL is the GraphQL implementation of GetConflictRateAndCount.
+func (a *Analyzer) conflictRateAndCountGraphQL(ctx context.Context, repo string) (float64, int, error) {
+	prs, err := a.listPullRequestsGraphQL(ctx, repo)
+	if err != nil {
+		return 0, 0, err
+	}
+	conflicts := 0
+	known := 0
+	for _, pr := range prs {
+		switch pr.Mergeable {
+		case githubv4.MergeableStateConflicting:
+			conflicts++
+			known++
+		case githubv4.MergeableStateMergeable:
+			known++
+		}
+	}
+	if known == 0 {
+		return 0, 0, nil
+	}
+	return float64(conflicts) / float64(known) * 100, conflicts, nil
+}
+
+// ErrGraphQLUnavailable is returned by metrics that only the GraphQL API can compute when the Analyzer
+// has no GraphQL client (it was created with NewAnalyzerWithProvider).
+var ErrGraphQLUnavailable = errors.New("metric requires the GraphQL API")
+
+// GetReviewThreadResolutionRate returns the percentage of review threads of the PRs merged in the period
+// that are marked resolved. The REST API doesn't expose thread resolution, so it always uses GraphQL,
+// whatever UseGraphQL says; Check only runs it with UseGraphQL. GraphQL only tells whether a thread is
+// resolved now, so threads resolved after the merge count as resolved too. Only the first 100 threads of
+// each PR are looked at.
+func (a *Analyzer) GetReviewThreadResolutionRate(ctx context.Context, repo string) (float64, error) {
+	if a.gql == nil {
+		return 0, ErrGraphQLUnavailable
+	}
+	prs, err := a.listPullRequestsGraphQL(ctx, repo)
+	if err != nil {
+		return 0, err
+	}
+
+	threads, resolved := 0, 0
+	for _, pr := range prs {
+		if pr.MergedAt == nil {
+			continue
+		}
+		for _, t := range pr.ReviewThreads.Nodes {
+			threads++
+			if t.IsResolved {
+				resolved++
+			}
+		}
+	}
+	if threads == 0 {
+		return 0, nil
+	}
+	return float64(resolved) / float64(threads) * 100, nil
+}
+
+// GetMaxThreadDepth returns the average, over the PRs in the period, of the longest review thread of each PR,
+// in comments (the first comment plus its replies), so a PR without review comments counts as 0.
+// Review comments are the only ones GitHub threads, hence issues are left out. Thread structure is only
+// exposed by GraphQL, so it always uses it, whatever UseGraphQL says; Check only runs it with UseGraphQL.
+func (a *Analyzer) GetMaxThreadDepth(ctx context.Context, repo string) (float64, error) {
+	if a.gql == nil {
+		return 0, ErrGraphQLUnavailable
+	}
+	prs, err := a.listPullRequestsGraphQL(ctx, repo)
+	if err != nil {
+		return 0, err
+	}
+	if len(prs) == 0 {
+		return 0, nil
+	}
+
+	total := 0
+	for _, pr := range prs {
+		deepest := 0
+		for _, t := range pr.ReviewThreads.Nodes {
+			deepest = max(deepest, t.Comments.TotalCount)
+		}
+		total += deepest
+	}
+	return float64(total) / float64(len(prs)), nil
+}
+
+// avgReviewersPerPRGraphQL is the GraphQL implementation of GetAvgReviewersPerPR.
+func (a *Analyzer) avgReviewersPerPRGraphQL(ctx context.Context, repo string) (float64, int, error) {
+	prs, err := a.listPullRequestsGraphQL(ctx, repo)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(prs) == 0 {
+		return 0, 0, nil
+	}
+	totalReviewers := 0
+	for _, pr := range prs {
+		uniqueReviewers := make(map[string]struct{})
+		for _, r := range pr.Reviews.Nodes {
+			if r.Author.Login != "" {
+				uniqueReviewers[r.Author.Login] = struct{}{}
+			}
+		}
+		totalReviewers += len(uniqueReviewers)
+	}
+	return float64(totalReviewers) / float64(len(prs)), 0, nil
+}
@@ -0,0 +1,75 @@
+package analyzer
+
+import (
+	"context"
+	"testing"
+)
+
+// TestGraphQLMatchesREST computes the PR metrics having a GraphQL path from the same PRs served by both APIs.
+func TestGraphQLMatchesREST(t *testing.T) {
+	routes := map[string]string{
+		"/search/issues": `{"total_count": 3, "items": [
+			{"number": 3, "state": "open", "created_at": "2024-01-20T10:00:00Z", "pull_request": {}},
+			{"number": 2, "state": "open", "created_at": "2024-01-12T10:00:00Z", "pull_request": {}},
+			{"number": 1, "state": "closed", "created_at": "2024-01-05T10:00:00Z", "pull_request": {}}
+		]}`,
+		"/repos/acme/api/pulls/3": `{"number": 3, "state": "open", "mergeable_state": "dirty"}`,
+		"/repos/acme/api/pulls/2": `{"number": 2, "state": "open", "mergeable_state": "clean"}`,
+		"/repos/acme/api/pulls/1": `{"number": 1, "state": "closed", "mergeable_state": "unknown"}`,
+		"/repos/acme/api/pulls/3/reviews": `[
+			{"user": {"login": "alice"}, "state": "COMMENTED"},
+			{"user": {"login": "bob"}, "state": "APPROVED"},
+			{"user": {"login": "alice"}, "state": "APPROVED"}
+		]`,
+		"/repos/acme/api/pulls/2/reviews": `[{"user": {"login": "carol"}, "state": "APPROVED"}]`,
+		"/repos/acme/api/pulls/1/reviews": `[]`,
+		"/graphql": `{"data": {
+			"repository": {"pullRequests": {
+				"nodes": [
+					{"number": 3, "createdAt": "2024-01-20T10:00:00Z", "updatedAt": "2024-01-21T10:00:00Z", "mergeable": "CONFLICTING",
+						"labels": {"nodes": []}, "reviewThreads": {"totalCount": 0, "nodes": []},
+						"reviews": {"nodes": [
+							{"author": {"login": "alice"}, "state": "COMMENTED"},
+							{"author": {"login": "bob"}, "state": "APPROVED"},
+							{"author": {"login": "alice"}, "state": "APPROVED"}
+						]}},
+					{"number": 2, "createdAt": "2024-01-12T10:00:00Z", "updatedAt": "2024-01-13T10:00:00Z", "mergeable": "MERGEABLE",
+						"labels": {"nodes": []}, "reviewThreads": {"totalCount": 0, "nodes": []},
+						"reviews": {"nodes": [{"author": {"login": "carol"}, "state": "APPROVED"}]}},
+					{"number": 1, "createdAt": "2024-01-05T10:00:00Z", "updatedAt": "2024-01-06T10:00:00Z", "mergeable": "UNKNOWN",
+						"closedAt": "2024-01-06T10:00:00Z", "labels": {"nodes": []}, "reviewThreads": {"totalCount": 0, "nodes": []},
+						"reviews": {"nodes": []}},
+					{"number": 0, "createdAt": "2023-12-20T10:00:00Z", "updatedAt": "2023-12-21T10:00:00Z", "mergeable": "CONFLICTING",
+						"labels": {"nodes": []}, "reviewThreads": {"totalCount": 0, "nodes": []}, "reviews": {"nodes": []}}
+				],
+				"pageInfo": {"endCursor": "Y3Vyc29y", "hasNextPage": false}
+			}},
+			"rateLimit": {"limit": 5000, "remaining": 4999, "resetAt": "2024-01-01T01:00:00Z"}
+		}}`,
+	}
+
+	results := make(map[bool][3]float64) // UseGraphQL -> conflict rate, conflict count, avg reviewers
+	for _, useGraphQL := range []bool{false, true} {
+		a := newTestAnalyzer(t, routes)
+		a.UseGraphQL = useGraphQL
+		ctx := context.Background()
+
+		rate, count, err := a.GetConflictRateAndCount(ctx, "api")
+		if err != nil {
+			t.Fatalf("UseGraphQL=%v: GetConflictRateAndCount: %v", useGraphQL, err)
+		}
+		reviewers, _, err := a.GetAvgReviewersPerPR(ctx, "api")
+		if err != nil {
+			t.Fatalf("UseGraphQL=%v: GetAvgReviewersPerPR: %v", useGraphQL, err)
+		}
+		results[useGraphQL] = [3]float64{rate, float64(count), reviewers}
+	}
+
+	want := [3]float64{50, 1, 1}
+	if results[false] != want {
+		t.Errorf("REST: conflict rate, count, avg reviewers = %v, want %v", results[false], want)
+	}
+	if results[true] != results[false] {
+		t.Errorf("GraphQL: conflict rate, count, avg reviewers = %v, want the REST ones %v", results[true], results[false])
+	}
+}
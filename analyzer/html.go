@@ -0,0 +1,163 @@
+package analyzer
+
+import (
+	"html/template"
+	"os"
+	"sort"
+)
+
+// ExportHTML exports the metrics to a self-contained HTML report, with one table per area and
+// inline SVG bar charts for the commit distribution and churn by directory of each repo, and its
+// TopChurnFiles most churned files.
+// The page has no external dependencies so it can be shared by e-mail; all names are escaped by html/template.
+// It returns the path of the file (see outputPath).
+func (a *Analyzer) ExportHTML(metrics []RepoMetrics, filename string) (string, error) {
+	path, err := a.outputPath(filename, ".html")
+	if err != nil {
+		return "", err
+	}
+
+	byArea := make(map[string][]RepoMetrics)
+	for _, m := range sortMetrics(metrics) {
+		byArea[m.Area] = append(byArea[m.Area], m)
+	}
+
+	var areas []htmlArea
+	for area, repos := range byArea {
+		areas = append(areas, htmlArea{Name: area, Repos: repos})
+	}
+	sort.Slice(areas, func(i, j int) bool { return areas[i].Name < areas[j].Name })
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	return path, htmlTemplate.Execute(f, htmlReport{
+		Owner:    a.Owner,
+		From:     a.StartDate.Format("02-01-2006"),
+		To:       a.EndDate.Format("02-01-2006"),
+		TopChurn: a.topChurnFiles(),
+		Areas:    areas,
+	})
+}
+
+type htmlReport struct {
+	Owner    string
+	From     string
+	To       string
+	TopChurn int // Files listed in the top churned files table of each repo
+	Areas    []htmlArea
+}
+
+type htmlArea struct {
+	Name  string
+	Repos []RepoMetrics
+}
+
+// htmlChart is an inline SVG bar chart.
+type htmlChart struct {
+	Title  string
+	Bars   []htmlBar
+	Height int
+}
+
+// htmlBar is a single bar of an inline SVG chart.
+type htmlBar struct {
+	Label string
+	Value int
+	Width int
+	Y     int
+}
+
+const (
+	htmlChartBars  = 15
+	htmlChartWidth = 300
+	htmlBarHeight  = 18
+)
+
+// newChart converts a count map into a chart of the largest htmlChartBars entries, scaled to htmlChartWidth.
+func newChart(title string, counts map[string]int) htmlChart {
+	var bars []htmlBar
+	for label, value := range counts {
+		bars = append(bars, htmlBar{Label: label, Value: value})
+	}
+	sort.Slice(bars, func(i, j int) bool {
+		if bars[i].Value != bars[j].Value {
+			return bars[i].Value > bars[j].Value
+		}
+		return bars[i].Label < bars[j].Label
+	})
+	if len(bars) > htmlChartBars {
+		bars = bars[:htmlChartBars]
+	}
+	for i := range bars {
+		bars[i].Y = i * htmlBarHeight
+		if bars[0].Value > 0 {
+			bars[i].Width = bars[i].Value * htmlChartWidth / bars[0].Value
+		}
+	}
+	return htmlChart{Title: title, Bars: bars, Height: len(bars) * htmlBarHeight}
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"chart":    newChart,
+	"topChurn": TopChurn,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>GitHub metrics - {{.Owner}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+th { background: #f0f0f0; }
+.charts { display: flex; flex-wrap: wrap; gap: 2em; margin-bottom: 2em; }
+svg text { font-size: 11px; }
+</style>
+</head>
+<body>
+<h1>GitHub metrics - {{.Owner}}</h1>
+<p>Period: {{.From}} to {{.To}}</p>
+{{range .Areas}}
+<h2>{{.Name}}</h2>
+<table>
+<tr>
+<th>Repo</th><th>Contributors</th><th>Conflict rate %</th><th>Avg merge (days)</th><th>Avg reviewers/PR</th>
+<th>Revert rate %</th><th>Workflow failures</th><th>Deploys</th><th>Deploys/day</th><th>Lead time (h)</th>
+<th>Change failure rate %</th><th>MTTR (h)</th><th>Rollback issues</th><th>Integration issues</th>
+</tr>
+{{range .Repos}}
+<tr>
+<td>{{.Repo}}</td><td>{{.UniqueContributors}}</td><td>{{printf "%.2f" .ConflictRate}}</td><td>{{printf "%.2f" .AvgMergeTimeDays}}</td><td>{{printf "%.2f" .AvgReviewersPerPR}}</td>
+<td>{{printf "%.2f" .RevertRate}}</td><td>{{.WorkflowFailures}}</td><td>{{.SuccessfulDeploys}}</td><td>{{printf "%.2f" .DeploymentsPerDay}}</td><td>{{printf "%.1f" .LeadTimeForChangesHours}}</td>
+<td>{{printf "%.2f" .ChangeFailureRate}}</td><td>{{printf "%.1f" .MTTRHours}}</td><td>{{.RollbackIssues}}</td><td>{{.IntegrationIssues}}</td>
+</tr>
+{{end}}
+</table>
+{{range .Repos}}
+<h3>{{.Repo}}</h3>
+<div class="charts">
+{{template "chart" (chart "Commits by contributor" .CommitDist)}}
+{{template "chart" (chart "Churn by directory" .ChurnByDir)}}
+{{with topChurn .ChurnByFile $.TopChurn}}<div>
+<h4>Top churned files</h4>
+<table>
+<tr><th>File</th><th>Commits</th></tr>
+{{range .}}<tr><td>{{.Path}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>
+</div>{{end}}
+</div>
+{{end}}
+{{end}}
+</body>
+</html>
+{{define "chart"}}{{if .Bars}}<div>
+<h4>{{.Title}}</h4>
+<svg xmlns="http://www.w3.org/2000/svg" width="560" height="{{.Height}}">
+{{range .Bars}}<g transform="translate(0,{{.Y}})"><text x="0" y="13">{{.Label}}</text><rect x="200" y="2" width="{{.Width}}" height="14" fill="#4a7fc1"></rect><text x="{{.Width}}" dx="205" y="13">{{.Value}}</text></g>
+{{end}}</svg>
+</div>{{end}}{{end}}`))
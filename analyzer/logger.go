@@ -0,0 +1,83 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+)
+
+// Logger receives the log output of an Analyzer.
+type Logger interface {
+	Infof(format string, args ...any)
+	Warnf(format string, args ...any)
+	Errorf(format string, args ...any)
+}
+
+// EventLogger is implemented by Loggers that keep the fields of an event (repo, metric, duration...)
+// structured. Loggers that don't implement it get the fields formatted as key=value after the message,
+// and no debug events.
+type EventLogger interface {
+	Event(level slog.Level, msg string, fields ...any)
+}
+
+// NewStdLogger returns a Logger writing to l, or to the standard logger when l is nil.
+func NewStdLogger(l *log.Logger) Logger {
+	if l == nil {
+		l = log.Default()
+	}
+	return stdLogger{l}
+}
+
+type stdLogger struct{ l *log.Logger }
+
+func (s stdLogger) Infof(format string, args ...any)  { s.l.Printf("INFO "+format, args...) }
+func (s stdLogger) Warnf(format string, args ...any)  { s.l.Printf("WARN "+format, args...) }
+func (s stdLogger) Errorf(format string, args ...any) { s.l.Printf("ERROR "+format, args...) }
+
+// NewJSONLogger returns a Logger writing one JSON object per line to w, with the fields of each event
+// as attributes, for consumption in CI.
+func NewJSONLogger(w io.Writer) Logger {
+	return jsonLogger{slog.New(slog.NewJSONHandler(w, nil))}
+}
+
+type jsonLogger struct{ l *slog.Logger }
+
+func (j jsonLogger) Infof(format string, args ...any)  { j.l.Info(fmt.Sprintf(format, args...)) }
+func (j jsonLogger) Warnf(format string, args ...any)  { j.l.Warn(fmt.Sprintf(format, args...)) }
+func (j jsonLogger) Errorf(format string, args ...any) { j.l.Error(fmt.Sprintf(format, args...)) }
+
+func (j jsonLogger) Event(level slog.Level, msg string, fields ...any) {
+	j.l.Log(context.Background(), level, msg, fields...)
+}
+
+// logEvent sends an event with its key/value fields to the Logger, if any.
+func (a *Analyzer) logEvent(level slog.Level, msg string, fields ...any) {
+	if a.Logger == nil {
+		return
+	}
+	if el, ok := a.Logger.(EventLogger); ok {
+		el.Event(level, msg, fields...)
+		return
+	}
+	if level < slog.LevelInfo {
+		// Infof/Warnf/Errorf have no debug level
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString(msg)
+	for i := 0; i+1 < len(fields); i += 2 {
+		fmt.Fprintf(&b, " %v=%v", fields[i], fields[i+1])
+	}
+	switch {
+	case level >= slog.LevelError:
+		a.Logger.Errorf("%s", b.String())
+	case level >= slog.LevelWarn:
+		a.Logger.Warnf("%s", b.String())
+	default:
+		a.Logger.Infof("%s", b.String())
+	}
+}
@@ -0,0 +1,99 @@
+package analyzer
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ExportMarkdown exports the metrics to a Markdown report with one table per area found in the metrics
+// and returns its path (see outputPath).
+func (a *Analyzer) ExportMarkdown(metrics []RepoMetrics, filename string) (string, error) {
+	path, err := a.outputPath(filename, ".md")
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# GitHub metrics - %s\n\n", a.Owner)
+	fmt.Fprintf(&b, "Period: %s to %s\n\n", a.StartDate.Format("02-01-2006"), a.EndDate.Format("02-01-2006"))
+
+	// Areas come from the results rather than a.Projects, so repos whose area is no longer
+	// in the configuration (e.g. results loaded from an older checkpoint) are still rendered
+	byArea := make(map[string][]RepoMetrics)
+	for _, m := range sortMetrics(metrics) {
+		byArea[m.Area] = append(byArea[m.Area], m)
+	}
+
+	var areas []string
+	for area := range byArea {
+		areas = append(areas, area)
+	}
+	sort.Strings(areas)
+
+	for _, area := range areas {
+		areaResults := byArea[area]
+
+		fmt.Fprintf(&b, "## %s\n\n", area)
+		b.WriteString("| Repo | Contributors | Unlinked commits | Conflict rate % | Avg merge (days) | Avg reviewers/PR | Revert rate % | Workflow failures | Deploys | Change failure rate % | MTTR (h) | Rollback issues | Integration issues |\n")
+		b.WriteString("|---|---|---|---|---|---|---|---|---|---|---|---|---|\n")
+		for _, m := range areaResults {
+			fmt.Fprintf(&b, "| %s | %d | %d | %.2f | %.2f | %.2f | %.2f | %d | %d | %.2f | %.1f | %d | %d |\n",
+				markdownEscape(m.Repo), m.UniqueContributors, m.UnlinkedCommits, m.ConflictRate, m.AvgMergeTimeDays,
+				m.AvgReviewersPerPR, m.RevertRate, m.WorkflowFailures, m.SuccessfulDeploys, m.ChangeFailureRate,
+				m.MTTRHours, m.RollbackIssues, m.IntegrationIssues)
+		}
+		b.WriteString("\n")
+
+		// Metrics that failed to fetch show as zeros in the table, so they're listed to keep them from passing as real values
+		for _, m := range areaResults {
+			if len(m.Errors) == 0 {
+				continue
+			}
+			failed := make([]string, 0, len(m.Errors))
+			for metric := range m.Errors {
+				failed = append(failed, metric)
+			}
+			sort.Strings(failed)
+			fmt.Fprintf(&b, "> **⚠️ Partial data for %s:** %d metrics failed to fetch: %s\n\n", markdownEscape(m.Repo), len(failed), strings.Join(failed, ", "))
+		}
+
+		// Churn maps can have thousands of entries, so only the most churned files are listed (the JSON export keeps them all)
+		for _, m := range areaResults {
+			top := TopChurn(m.ChurnByFile, a.topChurnFiles())
+			if len(top) == 0 {
+				continue
+			}
+			fmt.Fprintf(&b, "<details><summary>Top %d churned files of %s</summary>\n\n", len(top), markdownEscape(m.Repo))
+			b.WriteString("| File | Commits |\n|---|---|\n")
+			for _, f := range top {
+				fmt.Fprintf(&b, "| %s | %d |\n", markdownEscape(f.Path), f.Count)
+			}
+			b.WriteString("\n</details>\n\n")
+		}
+
+		// Merges without approval or by their own author are an audit signal, so they're called out separately
+		var flagged []RepoMetrics
+		for _, m := range areaResults {
+			if m.UnreviewedMerges > 0 || m.SelfMerges > 0 {
+				flagged = append(flagged, m)
+			}
+		}
+		if len(flagged) > 0 {
+			b.WriteString("> **⚠ Audit:** PRs merged without approval or by their own author\n>\n")
+			for _, m := range flagged {
+				fmt.Fprintf(&b, "> - **%s**: %d unreviewed merges, %d self-merges\n", markdownEscape(m.Repo), m.UnreviewedMerges, m.SelfMerges)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	return path, os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+// markdownEscape escapes the characters that would break a Markdown table cell.
+func markdownEscape(s string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(s)
+}
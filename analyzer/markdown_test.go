@@ -0,0 +1,48 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportMarkdownAreasFromResults(t *testing.T) {
+	p := &fakeProvider{repos: map[string]*fakeRepo{"api": {}, "ghost": {}}}
+	a := newFakeAnalyzer(p, map[string][]string{"core": {"api"}})
+
+	// ghost isn't in any area of the config
+	metrics, err := a.CheckRepos(context.Background(), []string{"api", "ghost"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range metrics {
+		if m.Repo == "ghost" && m.Area != UncategorizedArea {
+			t.Errorf("ghost: Area = %q, want %q", m.Area, UncategorizedArea)
+		}
+	}
+	// and legacy comes from a run whose config had an area that's gone since
+	metrics = append(metrics, RepoMetrics{Area: "legacy", Owner: "acme", Repo: "old-svc"})
+
+	path, err := a.ExportMarkdown(metrics, filepath.Join(t.TempDir(), "report.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := string(data)
+
+	// every repo under its area, areas sorted
+	last := 0
+	for _, section := range [][2]string{{UncategorizedArea, "ghost"}, {"core", "api"}, {"legacy", "old-svc"}} {
+		heading := strings.Index(report, "## "+section[0]+"\n")
+		row := strings.Index(report, "| "+section[1]+" |")
+		if heading < last || row < heading {
+			t.Fatalf("want %s under a %q heading, in area order, got:\n%s", section[1], section[0], report)
+		}
+		last = row
+	}
+}
@@ -0,0 +1,61 @@
+package analyzer
+
+import (
+	"fmt"
+	"sync"
+)
+
+// memo holds the results of list requests shared by several metrics of the same repo and period,
+// so that e.g. the commit list is fetched once per Check instead of once per metric.
+// Concurrent callers asking for the same key wait for a single fetch; failed fetches aren't stored.
+type memo struct {
+	mu      sync.Mutex
+	entries map[string]*memoEntry
+}
+
+type memoEntry struct {
+	mu    sync.Mutex
+	done  bool
+	value any
+}
+
+func newMemo() *memo {
+	return &memo{entries: make(map[string]*memoEntry)}
+}
+
+// reset drops every stored result.
+func (m *memo) reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = make(map[string]*memoEntry)
+}
+
+// memoize returns the value stored for key, calling fn to compute it on first use.
+func memoize[T any](m *memo, key string, fn func() (T, error)) (T, error) {
+	m.mu.Lock()
+	e, ok := m.entries[key]
+	if !ok {
+		e = &memoEntry{}
+		m.entries[key] = e
+	}
+	m.mu.Unlock()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.done {
+		return e.value.(T), nil
+	}
+	v, err := fn()
+	if err != nil {
+		return v, err
+	}
+	e.value = v
+	e.done = true
+	return v, nil
+}
+
+// memoKey builds the memo key of a kind of data for a repo in the current period.
+func (a *Analyzer) memoKey(kind, repo string) string {
+	owner, name := a.splitRepo(repo)
+	return fmt.Sprintf("%s:%s/%s:%d-%d", kind, owner, name, a.StartDate.UnixNano(), a.EndDate.UnixNano())
+}
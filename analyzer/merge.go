@@ -2,91 +2,675 @@ package analyzer
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strconv"
+	"net/http"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/go-github/v62/github"
 )
 
-// GetAvgMergeTime returns the average merge time in days for PRs in the period.
-func (a *Analyzer) GetAvgMergeTime(ctx context.Context, repo string) (float64, error) {
-	opts := &github.PullRequestListOptions{
-		State:       "closed",
-		Sort:        "created",
-		Direction:   "desc",
-		ListOptions: github.ListOptions{PerPage: 100},
+// GetAvgMergeTime returns the average merge time in days and the number of merged PRs in the period.
+// A single long-lived PR can skew the mean; GetMergeTimePercentiles is recommended for skewed distributions.
+func (a *Analyzer) GetAvgMergeTime(ctx context.Context, repo string) (float64, int, error) {
+	durations, err := a.mergeDurations(ctx, repo)
+	if err != nil {
+		return 0, 0, err
 	}
-	var allPRs []*github.PullRequest
 
-	for {
-		prs, resp, err := a.client.PullRequests.List(ctx, a.Owner, repo, opts)
-		if err != nil {
-			return 0, err
+	var totalDuration time.Duration
+	for _, d := range durations {
+		totalDuration += d
+	}
+	count := len(durations)
+	if count == 0 {
+		return 0, 0, nil
+	}
+	return totalDuration.Hours() / float64(count*24), count, nil
+}
+
+// GetPRMergeStats returns, from a single PR listing, the number of PRs merged in the period, the number of
+// PRs closed without being merged (abandoned) and the average merge time in days (see GetAvgMergeTime).
+// With TimeBasisMerged only merged PRs are in the period, so closedUnmerged is always 0.
+func (a *Analyzer) GetPRMergeStats(ctx context.Context, repo string) (merged, closedUnmerged int, avgMergeDays float64, err error) {
+	allPRs, err := a.listPullRequests(ctx, repo, "closed")
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	for _, pr := range allPRs {
+		if pr.MergedAt == nil {
+			closedUnmerged++
 		}
-		for _, pr := range prs {
-			if pr.CreatedAt.Time.After(a.StartDate) && pr.CreatedAt.Time.Before(a.EndDate) {
-				allPRs = append(allPRs, pr)
+	}
+
+	avgMergeDays, merged, err = a.GetAvgMergeTime(ctx, repo)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return merged, closedUnmerged, avgMergeDays, nil
+}
+
+// GetPRThroughput returns the number of PRs merged in the period per week, whenever they were created, so
+// long-lived PRs count in the week they land. Unless TimeBasis is TimeBasisMerged, that takes a PR listing of
+// its own (see listMergedPullRequests). Periods shorter than a week return the plain count instead of
+// extrapolating it.
+func (a *Analyzer) GetPRThroughput(ctx context.Context, repo string) (perWeek float64, err error) {
+	prs, err := a.listMergedPullRequests(ctx, repo)
+	if err != nil {
+		return 0, err
+	}
+	merged := len(prs)
+
+	weeks := a.EndDate.Sub(a.StartDate).Hours() / (24 * 7)
+	if weeks < 1 {
+		return float64(merged), nil
+	}
+	return float64(merged) / weeks, nil
+}
+
+// GetMergeTimePercentiles returns the median, 90th and 99th percentile merge times in days of the PRs
+// merged in the period, linearly interpolated between the closest ranks.
+func (a *Analyzer) GetMergeTimePercentiles(ctx context.Context, repo string) (p50, p90, p99 float64, err error) {
+	durations, err := a.mergeDurations(ctx, repo)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	var days []float64
+	for _, d := range durations {
+		days = append(days, d.Hours()/24)
+	}
+	if len(days) == 0 {
+		return 0, 0, 0, nil
+	}
+	sort.Float64s(days)
+	return percentile(days, 50), percentile(days, 90), percentile(days, 99), nil
+}
+
+// mergeDurations returns the time each PR merged in the period took to merge, measured from its creation.
+// With ExcludeDrafts, it's measured from the last time the PR was marked ready for review instead, so time
+// spent as a draft doesn't count. Listings don't tell whether a merged PR started as a draft, so this costs
+// one timeline request per merged PR; PRs whose timeline fails to load are left out.
+func (a *Analyzer) mergeDurations(ctx context.Context, repo string) ([]time.Duration, error) {
+	allPRs, err := a.listPullRequests(ctx, repo, "closed")
+	if err != nil {
+		return nil, err
+	}
+
+	var durations []time.Duration
+	var mu sync.Mutex
+	g, gctx := a.innerGroup(ctx)
+	for _, pr := range allPRs {
+		if pr.MergedAt == nil {
+			continue
+		}
+		if !a.ExcludeDrafts {
+			durations = append(durations, pr.MergedAt.Time.Sub(pr.CreatedAt.Time))
+			continue
+		}
+		if gctx.Err() != nil {
+			break
+		}
+		g.Go(func() error {
+			start := pr.CreatedAt.Time
+			ready, ok, err := a.readyForReviewAt(gctx, repo, pr.GetNumber())
+			if err != nil {
+				return hardError(err)
+			}
+			if ok {
+				start = ready
 			}
+			mu.Lock()
+			durations = append(durations, pr.MergedAt.Time.Sub(start))
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return durations, nil
+}
+
+// readyForReviewAt returns when a PR was last marked ready for review, if it ever was a draft.
+// A PR without a timeline (404) is reported as never a draft; other errors are returned.
+func (a *Analyzer) readyForReviewAt(ctx context.Context, repo string, number int) (time.Time, bool, error) {
+	events, err := a.listTimeline(ctx, repo, number)
+	if err != nil {
+		var errResp *github.ErrorResponse
+		if errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusNotFound {
+			return time.Time{}, false, nil
+		}
+		return time.Time{}, false, err
+	}
+	var ready time.Time
+	for _, e := range events {
+		if e.GetEvent() == "ready_for_review" && e.GetCreatedAt().After(ready) {
+			ready = e.GetCreatedAt().Time
 		}
-		if resp.NextPage == 0 {
+	}
+	return ready, !ready.IsZero(), nil
+}
+
+// listTimeline returns the timeline events of an issue or PR, shared by the metrics of a run.
+func (a *Analyzer) listTimeline(ctx context.Context, repo string, number int) ([]*github.Timeline, error) {
+	owner, name := a.splitRepo(repo)
+	return memoize(a.memo, a.memoKey(fmt.Sprintf("timeline#%d", number), repo), func() ([]*github.Timeline, error) {
+		opts := &github.ListOptions{PerPage: 100}
+		var events []*github.Timeline
+		for {
+			page, resp, err := doRequest(ctx, a, func() ([]*github.Timeline, *github.Response, error) {
+				return a.provider.ListIssueTimeline(ctx, owner, name, number, opts)
+			})
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, page...)
+			if a.lastPage(resp, opts.Page) {
+				break
+			}
+			opts.Page = resp.NextPage
+		}
+		return events, nil
+	})
+}
+
+// GetUnreviewedMerges returns the number of PRs merged in the period without a single approving review.
+func (a *Analyzer) GetUnreviewedMerges(ctx context.Context, repo string) (int, error) {
+	allPRs, err := a.listPullRequests(ctx, repo, "closed")
+	if err != nil {
+		return 0, err
+	}
+
+	unreviewed := 0
+	var mu sync.Mutex
+	g, gctx := a.innerGroup(ctx)
+	for _, pr := range allPRs {
+		if pr.MergedAt == nil {
+			continue
+		}
+		if gctx.Err() != nil {
 			break
 		}
-		opts.Page = resp.NextPage
-		a.checkRateLimit(resp)
+		prNum := pr.GetNumber()
+		g.Go(func() error {
+			reviews, err := a.listReviews(gctx, repo, prNum)
+			if err != nil {
+				return hardError(err)
+			}
+			for _, r := range reviews {
+				if r.GetState() == "APPROVED" {
+					return nil
+				}
+			}
+			mu.Lock()
+			unreviewed++
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return 0, err
 	}
 
-	var totalDuration time.Duration
-	count := 0
+	return unreviewed, nil
+}
+
+// GetSelfMerges returns the number of PRs merged in the period by their own author.
+// Listed PRs don't carry merged_by, so each merged PR is fetched individually.
+func (a *Analyzer) GetSelfMerges(ctx context.Context, repo string) (int, error) {
+	allPRs, err := a.listPullRequests(ctx, repo, "closed")
+	if err != nil {
+		return 0, err
+	}
+
+	selfMerges := 0
+	var mu sync.Mutex
+	g, gctx := a.innerGroup(ctx)
 	for _, pr := range allPRs {
-		if pr.MergedAt != nil {
-			delta := pr.MergedAt.Time.Sub(pr.CreatedAt.Time)
-			totalDuration += delta
-			count++
+		if pr.MergedAt == nil {
+			continue
+		}
+		if gctx.Err() != nil {
+			break
 		}
+		g.Go(func() error {
+			full, err := a.getPullRequest(gctx, repo, pr.GetNumber())
+			if err != nil {
+				return hardError(err)
+			}
+			author := full.GetUser().GetLogin()
+			if author != "" && full.GetMergedBy().GetLogin() == author {
+				mu.Lock()
+				selfMerges++
+				mu.Unlock()
+			}
+			return nil
+		})
 	}
-	if count == 0 {
-		return 0, nil
+	if err := g.Wait(); err != nil {
+		return 0, err
 	}
-	return totalDuration.Hours() / float64(count*24), nil
+
+	return selfMerges, nil
 }
 
-// GetAvgReviewersPerPR returns the average number of reviewers per PR and cross-team reviews.
-// For cross-team, this is a placeholder; implement with a user-to-team map if available.
-func (a *Analyzer) GetAvgReviewersPerPR(ctx context.Context, repo string) (float64, int, error) {
-	opts := &github.PullRequestListOptions{State: "all", Sort: "created", Direction: "desc", ListOptions: github.ListOptions{PerPage: 100}}
+// GetMergeMethodDistribution returns the number of PRs merged in the period by merge method:
+// "merge", "squash" or "rebase". The REST API doesn't expose the method, so it's inferred from the commit
+// the PR's merge_commit_sha points to:
+//   - two or more parents: a merge commit, "merge"
+//   - a single parent and a subject ending in "(#<number>)", the title GitHub gives squash commits: "squash"
+//   - otherwise the last of the PR's commits replayed onto the branch: "rebase"
+//
+// Squash commits whose title was edited to drop the PR number are counted as "rebase". PRs whose merge
+// commit couldn't be found count as "unknown". Merge commits outside the commit listing of the period need
+// one request each, besides one request per merged PR to get the merge commit.
+func (a *Analyzer) GetMergeMethodDistribution(ctx context.Context, repo string) (map[string]int, error) {
+	owner, name := a.splitRepo(repo)
+	allPRs, err := a.listPullRequests(ctx, repo, "closed")
+	if err != nil {
+		return nil, err
+	}
+	commits, err := a.getCommits(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	bySHA := make(map[string]*github.RepositoryCommit, len(commits))
+	for _, c := range commits {
+		bySHA[c.GetSHA()] = c
+	}
+
+	dist := make(map[string]int)
+	var mu sync.Mutex
+	g, gctx := a.innerGroup(ctx)
+	for _, pr := range allPRs {
+		if pr.MergedAt == nil {
+			continue
+		}
+		if gctx.Err() != nil {
+			break
+		}
+		g.Go(func() error {
+			method := "unknown"
+			defer func() {
+				mu.Lock()
+				dist[method]++
+				mu.Unlock()
+			}()
+
+			sha := pr.GetMergeCommitSHA()
+			if sha == "" {
+				// PRs from search results don't carry the merge commit
+				full, err := a.getPullRequest(gctx, repo, pr.GetNumber())
+				if err != nil {
+					return hardError(err)
+				}
+				sha = full.GetMergeCommitSHA()
+			}
+			if sha == "" {
+				return nil
+			}
+
+			commit, ok := bySHA[sha]
+			if !ok {
+				var err error
+				commit, _, err = doRequest(gctx, a, func() (*github.RepositoryCommit, *github.Response, error) {
+					return a.provider.GetCommit(gctx, owner, name, sha, nil)
+				})
+				if err != nil {
+					return hardError(err)
+				}
+			}
+			method = mergeMethod(commit, pr.GetNumber())
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return dist, nil
+}
+
+// mergeMethod infers how the PR numbered number was merged from its merge commit (see GetMergeMethodDistribution).
+func mergeMethod(commit *github.RepositoryCommit, number int) string {
+	if len(commit.Parents) > 1 {
+		return "merge"
+	}
+	subject, _, _ := strings.Cut(commit.GetCommit().GetMessage(), "\n")
+	if strings.HasSuffix(strings.TrimSpace(subject), fmt.Sprintf("(#%d)", number)) {
+		return "squash"
+	}
+	return "rebase"
+}
+
+// GetAvgPRSize returns the average number of files and of lines (additions + deletions) changed by the PRs
+// in the period. Listed PRs don't carry their size, so each PR is fetched individually; the fetch is shared
+// with conflict detection.
+func (a *Analyzer) GetAvgPRSize(ctx context.Context, repo string) (avgFiles float64, avgLines float64, err error) {
+	files, lines, err := a.prSizes(ctx, repo)
+	if err != nil || len(files) == 0 {
+		return 0, 0, err
+	}
+
+	totalFiles, totalLines := 0, 0
+	for i := range files {
+		totalFiles += files[i]
+		totalLines += lines[i]
+	}
+	return float64(totalFiles) / float64(len(files)), float64(totalLines) / float64(len(lines)), nil
+}
+
+// GetPRLinesChangedPercentile returns the p-th percentile (0-100) of the lines changed by the PRs in the period,
+// to spot large-PR outliers the average of GetAvgPRSize hides.
+func (a *Analyzer) GetPRLinesChangedPercentile(ctx context.Context, repo string, p float64) (float64, error) {
+	_, lines, err := a.prSizes(ctx, repo)
+	if err != nil || len(lines) == 0 {
+		return 0, err
+	}
+
+	sorted := make([]float64, len(lines))
+	for i, n := range lines {
+		sorted[i] = float64(n)
+	}
+	sort.Float64s(sorted)
+	return percentile(sorted, p), nil
+}
+
+// prSizes returns the files and lines changed by each PR in the period, in the same order.
+// PRs whose fetch fails are left out.
+func (a *Analyzer) prSizes(ctx context.Context, repo string) (files, lines []int, err error) {
+	allPRs, err := a.listPullRequests(ctx, repo, "all")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var mu sync.Mutex
+	g, gctx := a.innerGroup(ctx)
+	for _, pr := range allPRs {
+		if gctx.Err() != nil {
+			break
+		}
+		prNum := pr.GetNumber()
+		g.Go(func() error {
+			full, err := a.getPullRequest(gctx, repo, prNum)
+			if err != nil {
+				return hardError(err)
+			}
+			mu.Lock()
+			files = append(files, full.GetChangedFiles())
+			lines = append(lines, full.GetAdditions()+full.GetDeletions())
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, nil, err
+	}
+
+	return files, lines, nil
+}
+
+// getPullRequest returns the full PR, with the fields listings leave out (merged_by, merge commit SHA...).
+// It's shared by the metrics of a run.
+func (a *Analyzer) getPullRequest(ctx context.Context, repo string, number int) (*github.PullRequest, error) {
+	owner, name := a.splitRepo(repo)
+	return memoize(a.memo, a.memoKey(fmt.Sprintf("pr#%d", number), repo), func() (*github.PullRequest, error) {
+		pr, _, err := doRequest(ctx, a, func() (*github.PullRequest, *github.Response, error) {
+			return a.provider.GetPullRequest(ctx, owner, name, number)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return pr, nil
+	})
+}
+
+// listPullRequests returns the PRs in the given state ("open", "closed" or "all") in the period (see TimeBasis).
+// The Search API is used so that GitHub filters by date server-side; since search results are capped at
+// searchResultCap, larger result sets fall back to paginating the REST list. PRs built from search results
+// only carry the fields of an issue (no head/base refs, merge commit SHA or mergeable state).
+// The result is shared by every PR-based metric of a run. Only PRs targeting BaseBranchFilter are listed,
+// with PRLabelFilter only PRs carrying one of its labels, and with ExcludeDrafts, PRs still in draft are left out.
+func (a *Analyzer) listPullRequests(ctx context.Context, repo, state string) ([]*github.PullRequest, error) {
+	prs, err := memoize(a.memo, a.memoKey("prs-"+state, repo), func() ([]*github.PullRequest, error) {
+		return a.fetchPullRequests(ctx, repo, state, a.TimeBasis)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a.keepPRs(prs), nil
+}
+
+// listMergedPullRequests returns the PRs merged in the period whatever TimeBasis says, e.g. including PRs
+// created before the period under TimeBasisCreated. With TimeBasisMerged it's the shared listing of closed PRs;
+// otherwise it's a listing of its own, shared by the metrics of a run needing it. The filters of
+// listPullRequests apply.
+func (a *Analyzer) listMergedPullRequests(ctx context.Context, repo string) ([]*github.PullRequest, error) {
+	if a.TimeBasis == TimeBasisMerged {
+		return a.listPullRequests(ctx, repo, "closed")
+	}
+	prs, err := memoize(a.memo, a.memoKey("prs-merged", repo), func() ([]*github.PullRequest, error) {
+		return a.fetchPullRequests(ctx, repo, "closed", TimeBasisMerged)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a.keepPRs(prs), nil
+}
+
+// fetchPullRequests lists the PRs in the given state that are in the period according to basis, through
+// the Search API or, for larger result sets, the REST list (see listPullRequests).
+func (a *Analyzer) fetchPullRequests(ctx context.Context, repo, state string, basis TimeBasis) ([]*github.PullRequest, error) {
+	prs, complete, err := a.searchPullRequests(ctx, repo, state, basis)
+	if err != nil {
+		return nil, err
+	}
+	if complete {
+		return prs, nil
+	}
+	return a.listPullRequestsREST(ctx, repo, state, basis)
+}
+
+// keepPRs returns the PRs passing keepPR, prs itself when no filter is set.
+func (a *Analyzer) keepPRs(prs []*github.PullRequest) []*github.PullRequest {
+	if !a.ExcludeDrafts && len(a.PRLabelFilter) == 0 {
+		return prs
+	}
+	var kept []*github.PullRequest
+	for _, pr := range prs {
+		if a.keepPR(pr) {
+			kept = append(kept, pr)
+		}
+	}
+	return kept
+}
+
+// keepPR reports whether a listed PR passes ExcludeDrafts and PRLabelFilter.
+func (a *Analyzer) keepPR(pr *github.PullRequest) bool {
+	if a.ExcludeDrafts && pr.GetDraft() {
+		return false
+	}
+	return len(a.PRLabelFilter) == 0 || hasLabel(&github.Issue{Labels: pr.Labels}, a.PRLabelFilter)
+}
+
+// searchResultCap is the maximum number of results the Search API returns for a query.
+const searchResultCap = 1000
+
+// searchPullRequests lists the PRs in the period according to basis through the Search API.
+// It returns complete=false without paginating when the query matches more than searchResultCap PRs.
+func (a *Analyzer) searchPullRequests(ctx context.Context, repo, state string, basis TimeBasis) ([]*github.PullRequest, bool, error) {
+	owner, name := a.splitRepo(repo)
+	query := fmt.Sprintf("repo:%s/%s is:pr %s:%s..%s", owner, name, basis.qualifier(),
+		a.StartDate.UTC().Format("2006-01-02T15:04:05Z"), a.EndDate.UTC().Format("2006-01-02T15:04:05Z"))
+	if state == "open" || state == "closed" {
+		query += " is:" + state
+	}
+	if a.BaseBranchFilter != "" {
+		query += " base:" + a.BaseBranchFilter
+	}
+
+	opts := &github.SearchOptions{Sort: "created", Order: "desc", ListOptions: github.ListOptions{PerPage: 100}}
+	var allPRs []*github.PullRequest
+	for {
+		result, resp, err := doRequest(ctx, a, func() (*github.IssuesSearchResult, *github.Response, error) {
+			return a.provider.SearchIssues(ctx, query, opts)
+		})
+		if err != nil {
+			return nil, false, err
+		}
+		if result.GetTotal() > searchResultCap {
+			return nil, false, nil
+		}
+		for _, issue := range result.Issues {
+			allPRs = append(allPRs, issueToPullRequest(issue))
+		}
+		if a.lastPage(resp, opts.Page) {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return allPRs, true, nil
+}
+
+// listPullRequestsREST lists the PRs in the period according to basis by paginating the REST list endpoint.
+// PRs are sorted newest first by creation, or by update for the other bases (merging or closing a PR
+// updates it), so listing stops at the first PR older than the period.
+func (a *Analyzer) listPullRequestsREST(ctx context.Context, repo, state string, basis TimeBasis) ([]*github.PullRequest, error) {
+	owner, name := a.splitRepo(repo)
+	sortBy := "created"
+	if basis != TimeBasisCreated {
+		sortBy = "updated"
+	}
+	opts := &github.PullRequestListOptions{State: state, Base: a.BaseBranchFilter, Sort: sortBy, Direction: "desc", ListOptions: github.ListOptions{PerPage: 100}}
 	var allPRs []*github.PullRequest
 	for {
-		prs, resp, err := a.client.PullRequests.List(ctx, a.Owner, repo, opts)
+		prs, resp, err := doRequest(ctx, a, func() ([]*github.PullRequest, *github.Response, error) {
+			return a.provider.ListPullRequests(ctx, owner, name, opts)
+		})
 		if err != nil {
-			return 0, 0, err
+			return nil, err
 		}
 		for _, pr := range prs {
-			if pr.CreatedAt.After(a.StartDate) && pr.CreatedAt.Before(a.EndDate) {
+			sortedAt := pr.CreatedAt
+			if sortBy == "updated" {
+				sortedAt = pr.UpdatedAt
+			}
+			if sortedAt.Before(a.StartDate) {
+				// sorted newest first: nothing older is in the period
+				return allPRs, nil
+			}
+			if a.prInPeriod(pr, basis) {
 				allPRs = append(allPRs, pr)
 			}
 		}
-		if resp.NextPage == 0 {
+		if a.lastPage(resp, opts.Page) {
 			break
 		}
 		opts.Page = resp.NextPage
-		a.checkRateLimit(resp)
+	}
+	return allPRs, nil
+}
+
+// issueToPullRequest converts a PR returned by the Search API (as an issue) into a PullRequest.
+func issueToPullRequest(issue *github.Issue) *github.PullRequest {
+	pr := &github.PullRequest{
+		Number:    issue.Number,
+		Title:     issue.Title,
+		State:     issue.State,
+		User:      issue.User,
+		Labels:    issue.Labels,
+		Draft:     issue.Draft,
+		HTMLURL:   issue.HTMLURL,
+		CreatedAt: issue.CreatedAt,
+		UpdatedAt: issue.UpdatedAt,
+		ClosedAt:  issue.ClosedAt,
+	}
+	if issue.PullRequestLinks != nil {
+		pr.MergedAt = issue.PullRequestLinks.MergedAt
+	}
+	return pr
+}
+
+// GetStalePRCount returns the number of open PRs that, at EndDate, had been open for longer than staleThreshold.
+// Open means open at the time of the run. Draft PRs are only counted when CountDrafts is set, and
+// PRLabelFilter applies as for the other PR metrics.
+func (a *Analyzer) GetStalePRCount(ctx context.Context, repo string, staleThreshold time.Duration) (int, error) {
+	prs, err := a.listOpenPullRequests(ctx, repo)
+	if err != nil {
+		return 0, err
+	}
+	staleBefore := a.EndDate.Add(-staleThreshold)
+	count := 0
+	for _, pr := range prs {
+		if pr.GetDraft() && !a.CountDrafts {
+			continue
+		}
+		if len(a.PRLabelFilter) > 0 && !hasLabel(&github.Issue{Labels: pr.Labels}, a.PRLabelFilter) {
+			continue
+		}
+		if pr.CreatedAt.Before(staleBefore) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// listOpenPullRequests returns the currently open PRs created before the end of the period, regardless of StartDate.
+func (a *Analyzer) listOpenPullRequests(ctx context.Context, repo string) ([]*github.PullRequest, error) {
+	owner, name := a.splitRepo(repo)
+	return memoize(a.memo, a.memoKey("open-prs", repo), func() ([]*github.PullRequest, error) {
+		opts := &github.PullRequestListOptions{State: "open", Base: a.BaseBranchFilter, Sort: "created", Direction: "desc", ListOptions: github.ListOptions{PerPage: 100}}
+		var allPRs []*github.PullRequest
+		for {
+			prs, resp, err := doRequest(ctx, a, func() ([]*github.PullRequest, *github.Response, error) {
+				return a.provider.ListPullRequests(ctx, owner, name, opts)
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, pr := range prs {
+				if pr.CreatedAt.Before(a.EndDate) {
+					allPRs = append(allPRs, pr)
+				}
+			}
+			if a.lastPage(resp, opts.Page) {
+				break
+			}
+			opts.Page = resp.NextPage
+		}
+		return allPRs, nil
+	})
+}
+
+// GetAvgReviewersPerPR returns the average number of reviewers per PR and cross-team reviews.
+// For cross-team, this is a placeholder; implement with a user-to-team map if available.
+func (a *Analyzer) GetAvgReviewersPerPR(ctx context.Context, repo string) (float64, int, error) {
+	if a.UseGraphQL && a.gql != nil {
+		return a.avgReviewersPerPRGraphQL(ctx, repo)
+	}
+
+	allPRs, err := a.listPullRequests(ctx, repo, "all")
+	if err != nil {
+		return 0, 0, err
 	}
 
 	totalReviewers := 0
 	crossTeam := 0 // Placeholder for cross-team count
 	countPRs := 0
 	var mu sync.Mutex
-	wg := sync.WaitGroup{}
-	sem := make(chan struct{}, 10)
+	g, gctx := a.innerGroup(ctx)
 	for _, pr := range allPRs {
-		wg.Add(1)
-		go func(prNum int) {
-			defer wg.Done()
-			sem <- struct{}{}
-			defer func() { <-sem }()
-			reviews, resp, err := a.client.PullRequests.ListReviews(ctx, a.Owner, repo, prNum, &github.ListOptions{PerPage: 100})
+		if gctx.Err() != nil {
+			break
+		}
+		prNum := *pr.Number
+		g.Go(func() error {
+			reviews, err := a.listReviews(gctx, repo, prNum)
 			if err == nil {
 				uniqueReviewers := make(map[string]struct{})
 				for _, r := range reviews {
@@ -99,10 +683,12 @@ func (a *Analyzer) GetAvgReviewersPerPR(ctx context.Context, repo string) (float
 				countPRs++
 				mu.Unlock()
 			}
-			a.checkRateLimit(resp)
-		}(*pr.Number)
+			return hardError(err)
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return 0, 0, err
 	}
-	wg.Wait()
 
 	if countPRs == 0 {
 		return 0, 0, nil
@@ -113,141 +699,227 @@ func (a *Analyzer) GetAvgReviewersPerPR(ctx context.Context, repo string) (float
 
 // GetSuccessfulReruns returns the count of successful workflow re-runs in the period.
 func (a *Analyzer) GetSuccessfulReruns(ctx context.Context, repo string) (int, error) {
-	workflowIDInt, err := strconv.ParseInt(a.WorkflowID, 10, 64)
+	runs, err := a.listWorkflowRuns(ctx, repo)
 	if err != nil {
-		return 0, fmt.Errorf("invalid workflow ID: %v", err)
+		return 0, err
 	}
-	opts := &github.ListWorkflowRunsOptions{Created: fmt.Sprintf("%s..%s", a.StartDate.Format("2006-01-02"), a.EndDate.Format("2006-01-02")), ListOptions: github.ListOptions{PerPage: 100}}
 	count := 0
-	for {
-		runs, resp, err := a.client.Actions.ListWorkflowRunsByID(ctx, a.Owner, repo, workflowIDInt, opts)
-		if err != nil {
-			return 0, err
-		}
-		for _, run := range runs.WorkflowRuns {
-			if *run.Conclusion == "success" && *run.RunAttempt > 1 {
-				count++
-			}
-		}
-		if resp.NextPage == 0 {
-			break
+	for _, run := range runs {
+		if run.GetConclusion() == "success" && run.GetRunAttempt() > 1 {
+			count++
 		}
-		opts.Page = resp.NextPage
-		a.checkRateLimit(resp)
 	}
 	return count, nil
 }
 
-// GetRollbackIssues returns the count of issues with rollback label in the period.
+// GetRollbackIssues returns the count of issues with any of the rollback labels in the period.
 func (a *Analyzer) GetRollbackIssues(ctx context.Context, repo string) (int, error) {
-	opts := &github.IssueListByRepoOptions{Labels: []string{"rollback"}, Since: a.StartDate, State: "all", ListOptions: github.ListOptions{PerPage: 100}}
-	count := 0
-	for {
-		issues, resp, err := a.client.Issues.ListByRepo(ctx, a.Owner, repo, opts)
-		if err != nil {
-			return 0, err
-		}
-		for _, i := range issues {
-			if i.CreatedAt.Before(a.EndDate) {
-				count++
+	issues, err := a.listIssues(ctx, repo, a.RollbackLabels)
+	if err != nil {
+		return 0, err
+	}
+	return len(issues), nil
+}
+
+// listIssues returns the issues carrying any of the given labels that are in the period (see TimeBasis).
+// GitHub only supports AND-ing labels in a single query, so each label is listed separately and the results are
+// deduplicated by issue number. Labels are matched case-insensitively.
+// PRs are left out unless IncludePRsInIssueMetrics is set.
+func (a *Analyzer) listIssues(ctx context.Context, repo string, labels []string) ([]*github.Issue, error) {
+	owner, name := a.splitRepo(repo)
+	seen := make(map[int]struct{})
+	var allIssues []*github.Issue
+	for _, label := range labels {
+		opts := &github.IssueListByRepoOptions{Labels: []string{label}, Since: a.StartDate, State: "all", ListOptions: github.ListOptions{PerPage: 100}}
+		for {
+			issues, resp, err := doRequest(ctx, a, func() ([]*github.Issue, *github.Response, error) {
+				return a.provider.ListIssues(ctx, owner, name, opts)
+			})
+			if err != nil {
+				return nil, err
 			}
+			for _, i := range issues {
+				if _, ok := seen[i.GetNumber()]; ok {
+					continue
+				}
+				// Since filters on update time, so the period must be checked on both ends
+				if a.issueInPeriod(i) && hasLabel(i, labels) && !a.skipIssue(i) {
+					seen[i.GetNumber()] = struct{}{}
+					allIssues = append(allIssues, i)
+				}
+			}
+			if a.lastPage(resp, opts.Page) {
+				break
+			}
+			opts.Page = resp.NextPage
 		}
-		if resp.NextPage == 0 {
-			break
-		}
-		opts.Page = resp.NextPage
-		a.checkRateLimit(resp)
 	}
-	return count, nil
+	return allIssues, nil
 }
 
-// GetAvgThreadDepth returns the average thread depth for issues/PRs in the period.
-func (a *Analyzer) GetAvgThreadDepth(ctx context.Context, repo string) (float64, error) {
-	// List issues
-	issueOpts := &github.IssueListByRepoOptions{Since: a.StartDate, State: "all", ListOptions: github.ListOptions{PerPage: 100}}
-	var allIssues []*github.Issue
-	for {
-		issues, resp, err := a.client.Issues.ListByRepo(ctx, a.Owner, repo, issueOpts)
-		if err != nil {
-			return 0, err
+// GetIssueCountsByLabel returns the number of issues in the period (see TimeBasis) carrying each of the given labels.
+// Issues are listed once and bucketed by label, instead of one query per label; labels are matched
+// case-insensitively and an issue with several of them counts under each.
+func (a *Analyzer) GetIssueCountsByLabel(ctx context.Context, repo string, labels []string) (map[string]int, error) {
+	issues, err := a.listAllIssues(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(labels))
+	for _, label := range labels {
+		counts[label] = 0
+	}
+	for _, i := range issues {
+		if !a.issueInPeriod(i) || a.skipIssue(i) {
+			continue
 		}
-		for _, i := range issues {
-			if i.CreatedAt.Before(a.EndDate) {
-				allIssues = append(allIssues, i)
+		for _, label := range labels {
+			if hasLabel(i, []string{label}) {
+				counts[label]++
 			}
 		}
-		if resp.NextPage == 0 {
-			break
-		}
-		issueOpts.Page = resp.NextPage
-		a.checkRateLimit(resp)
 	}
+	return counts, nil
+}
 
-	// List PRs (similar to issues for comments)
-	prOpts := &github.PullRequestListOptions{State: "all", Sort: "created", Direction: "desc", ListOptions: github.ListOptions{PerPage: 100}}
-	var allPRs []*github.PullRequest
-	for {
-		prs, resp, err := a.client.PullRequests.List(ctx, a.Owner, repo, prOpts)
-		if err != nil {
-			return 0, err
+// GetIssueThroughput returns the number of issues opened in the period, the number closed in the period and
+// the average time to close (ClosedAt - CreatedAt) of the closed ones. PRs, which the issues endpoint also
+// returns, are left out.
+func (a *Analyzer) GetIssueThroughput(ctx context.Context, repo string) (opened, closed int, avgResolution time.Duration, err error) {
+	issues, err := a.listAllIssues(ctx, repo)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	var total time.Duration
+	for _, i := range issues {
+		if i.IsPullRequest() {
+			continue
 		}
-		for _, pr := range prs {
-			if pr.CreatedAt.After(a.StartDate) && pr.CreatedAt.Before(a.EndDate) {
-				allPRs = append(allPRs, pr)
-			}
+		if i.CreatedAt.After(a.StartDate) {
+			opened++
 		}
-		if resp.NextPage == 0 {
-			break
+		if i.ClosedAt != nil && i.ClosedAt.After(a.StartDate) && i.ClosedAt.Before(a.EndDate) {
+			closed++
+			total += i.ClosedAt.Sub(i.CreatedAt.Time)
 		}
-		prOpts.Page = resp.NextPage
-		a.checkRateLimit(resp)
+	}
+
+	if closed == 0 {
+		return opened, 0, 0, nil
+	}
+	return opened, closed, total / time.Duration(closed), nil
+}
+
+// skipIssue reports whether an issue returned by the issues endpoint is really a PR to be left out of
+// issue-based metrics, which is the case unless IncludePRsInIssueMetrics is set.
+func (a *Analyzer) skipIssue(i *github.Issue) bool {
+	return i.IsPullRequest() && !a.IncludePRsInIssueMetrics
+}
+
+// listAllIssues returns the issues updated since StartDate and created before EndDate, regardless of labels.
+// The list is shared by every issue-based metric of a run.
+func (a *Analyzer) listAllIssues(ctx context.Context, repo string) ([]*github.Issue, error) {
+	owner, name := a.splitRepo(repo)
+	return memoize(a.memo, a.memoKey("issues", repo), func() ([]*github.Issue, error) {
+		opts := &github.IssueListByRepoOptions{Since: a.StartDate, State: "all", ListOptions: github.ListOptions{PerPage: 100}}
+		var allIssues []*github.Issue
+		for {
+			issues, resp, err := doRequest(ctx, a, func() ([]*github.Issue, *github.Response, error) {
+				return a.provider.ListIssues(ctx, owner, name, opts)
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, i := range issues {
+				if i.CreatedAt.Before(a.EndDate) {
+					allIssues = append(allIssues, i)
+				}
+			}
+			if a.lastPage(resp, opts.Page) {
+				break
+			}
+			opts.Page = resp.NextPage
+		}
+		return allIssues, nil
+	})
+}
+
+// GetAvgCommentsPerItem returns the average number of comments per issue or PR in the period: issue comments
+// for issues, review comments for PRs. It says how much discussion there is, not how deep it goes; see
+// GetMaxThreadDepth for the length of the reply chains.
+// PRs are counted once, from the PR list, unless IncludePRsInIssueMetrics also counts them as issues.
+func (a *Analyzer) GetAvgCommentsPerItem(ctx context.Context, repo string) (float64, error) {
+	owner, name := a.splitRepo(repo)
+	// List issues
+	allIssues, err := a.listAllIssues(ctx, repo)
+	if err != nil {
+		return 0, err
+	}
+
+	// List PRs (similar to issues for comments)
+	allPRs, err := a.listPullRequests(ctx, repo, "all")
+	if err != nil {
+		return 0, err
 	}
 
 	totalComments := 0
-	totalItems := len(allIssues) + len(allPRs)
+	totalItems := len(allPRs)
+	for _, issue := range allIssues {
+		if !a.skipIssue(issue) && a.issueInPeriod(issue) {
+			totalItems++
+		}
+	}
 	if totalItems == 0 {
 		return 0, nil
 	}
 
 	var mu sync.Mutex
-	wg := sync.WaitGroup{}
-	sem := make(chan struct{}, 10)
+	g, gctx := a.innerGroup(ctx)
 
 	// For issues
 	for _, issue := range allIssues {
-		wg.Add(1)
-		go func(num int) {
-			defer wg.Done()
-			sem <- struct{}{}
-			defer func() { <-sem }()
-			comments, resp, err := a.client.Issues.ListComments(ctx, a.Owner, repo, num, &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}})
+		if a.skipIssue(issue) || !a.issueInPeriod(issue) {
+			continue
+		}
+		if gctx.Err() != nil {
+			break
+		}
+		num := *issue.Number
+		g.Go(func() error {
+			comments, err := a.listIssueComments(gctx, repo, num)
 			if err == nil {
 				mu.Lock()
 				totalComments += len(comments)
 				mu.Unlock()
 			}
-			a.checkRateLimit(resp)
-		}(*issue.Number)
+			return hardError(err)
+		})
 	}
 
 	// For PRs
 	for _, pr := range allPRs {
-		wg.Add(1)
-		go func(num int) {
-			defer wg.Done()
-			sem <- struct{}{}
-			defer func() { <-sem }()
-			comments, resp, err := a.client.PullRequests.ListComments(ctx, a.Owner, repo, num, &github.PullRequestListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}})
+		if gctx.Err() != nil {
+			break
+		}
+		num := *pr.Number
+		g.Go(func() error {
+			comments, _, err := doRequest(gctx, a, func() ([]*github.PullRequestComment, *github.Response, error) {
+				return a.provider.ListReviewComments(gctx, owner, name, num, &github.PullRequestListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}})
+			})
 			if err == nil {
 				mu.Lock()
 				totalComments += len(comments)
 				mu.Unlock()
 			}
-			a.checkRateLimit(resp)
-		}(*pr.Number)
+			return hardError(err)
+		})
 	}
 
-	wg.Wait()
+	if err := g.Wait(); err != nil {
+		return 0, err
+	}
 
 	return float64(totalComments) / float64(totalItems), nil
 }
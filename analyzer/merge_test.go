@@ -0,0 +1,178 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// fakeIssue returns an issue created and last updated the given number of days after the start of the period.
+func fakeIssue(number int, label string, createdDay, updatedDay int) *github.Issue {
+	return &github.Issue{
+		Number:    github.Int(number),
+		State:     github.String("closed"),
+		Labels:    []*github.Label{{Name: github.String(label)}},
+		CreatedAt: &github.Timestamp{Time: testStart.AddDate(0, 0, createdDay)},
+		UpdatedAt: &github.Timestamp{Time: testStart.AddDate(0, 0, updatedDay)},
+	}
+}
+
+func TestLabeledIssuesOutsideThePeriod(t *testing.T) {
+	p := &fakeProvider{repos: map[string]*fakeRepo{"api": {issues: []*github.Issue{
+		fakeIssue(1, "rollback", 3, 4),
+		fakeIssue(2, "Rollback", 10, 40),        // label case differs, updated after the period
+		fakeIssue(3, "rollback", -60, 5),        // created long before the period, updated in it
+		fakeIssue(4, "rollback", -1, -1),        // created and updated the day before the period
+		fakeIssue(5, "rollback", 35, 36),        // created after the period
+		fakeIssue(6, "bug-integration", 7, 8),   // in the period
+		fakeIssue(7, "bug-integration", -90, 2), // created before the period, updated in it
+	}}}}
+	a := newFakeAnalyzer(p, map[string][]string{"core": {"api"}})
+	ctx := context.Background()
+
+	rollbacks, err := a.GetRollbackIssues(ctx, "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rollbacks != 2 {
+		t.Errorf("GetRollbackIssues = %d, want 2 (#1 and #2)", rollbacks)
+	}
+
+	integration, err := a.GetIntegrationIssues(ctx, "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if integration != 1 {
+		t.Errorf("GetIntegrationIssues = %d, want 1 (#6)", integration)
+	}
+}
+
+// fakePR returns a PR created the given number of days after the start of the period and merged a day later.
+func fakePR(number, createdDay int) *github.PullRequest {
+	return &github.PullRequest{
+		Number:         github.Int(number),
+		State:          github.String("closed"),
+		MergeableState: github.String("clean"),
+		CreatedAt:      &github.Timestamp{Time: testStart.AddDate(0, 0, createdDay)},
+		UpdatedAt:      &github.Timestamp{Time: testStart.AddDate(0, 0, createdDay+1)},
+		MergedAt:       &github.Timestamp{Time: testStart.AddDate(0, 0, createdDay+1)},
+		ClosedAt:       &github.Timestamp{Time: testStart.AddDate(0, 0, createdDay+1)},
+	}
+}
+
+func TestListPullRequestsStopsPaginating(t *testing.T) {
+	// newest first, 2 per page: the 5 PRs of the period fill pages 1-3, followed by older ones
+	var prs []*github.PullRequest
+	for i := 0; i < 5; i++ {
+		prs = append(prs, fakePR(10-i, 20-i))
+	}
+	for i := 0; i < 5; i++ {
+		prs = append(prs, fakePR(5-i, -10-i))
+	}
+
+	tests := []struct {
+		name      string
+		maxPages  int
+		wantPRs   int
+		wantPages int
+	}{
+		{"past the period", 0, 5, 3},
+		{"MaxPages", 2, 4, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &fakeProvider{perPage: 2, repos: map[string]*fakeRepo{"api": {prs: prs}}}
+			a := newFakeAnalyzer(p, map[string][]string{"core": {"api"}})
+			a.MaxPages = tt.maxPages
+
+			got, err := a.listPullRequests(context.Background(), "api", "closed")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.wantPRs {
+				t.Errorf("listPullRequests returned %d PRs, want %d", len(got), tt.wantPRs)
+			}
+			if pages := p.callsTo("ListPullRequests"); pages != tt.wantPages {
+				t.Errorf("listPullRequests fetched %d pages, want %d", pages, tt.wantPages)
+			}
+		})
+	}
+}
+
+func TestRollbackIssuesLeaveOutPRs(t *testing.T) {
+	pr := fakeIssue(2, "rollback", 5, 6)
+	pr.PullRequestLinks = &github.PullRequestLinks{URL: github.String("https://api.github.com/repos/acme/api/pulls/2")}
+	p := &fakeProvider{repos: map[string]*fakeRepo{"api": {issues: []*github.Issue{fakeIssue(1, "rollback", 3, 4), pr}}}}
+
+	for _, includePRs := range []bool{false, true} {
+		a := newFakeAnalyzer(p, map[string][]string{"core": {"api"}})
+		a.IncludePRsInIssueMetrics = includePRs
+		want := 1
+		if includePRs {
+			want = 2
+		}
+		got, err := a.GetRollbackIssues(context.Background(), "api")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("IncludePRsInIssueMetrics=%v: GetRollbackIssues = %d, want %d", includePRs, got, want)
+		}
+	}
+}
+
+func TestAvgMergeTimeExcludeDrafts(t *testing.T) {
+	readyAt := &github.Timestamp{Time: testStart.AddDate(0, 0, 10).Add(12 * time.Hour)}
+	repo := &fakeRepo{
+		prs: []*github.PullRequest{fakePR(3, 10), fakePR(2, 5)},
+		// #3 was a draft for its first 12 hours; #2 has no timeline
+		timelines: map[int][]*github.Timeline{3: {{Event: github.String("ready_for_review"), CreatedAt: readyAt}}},
+	}
+
+	t.Run("measured from ready for review", func(t *testing.T) {
+		a := newFakeAnalyzer(&fakeProvider{repos: map[string]*fakeRepo{"api": repo}}, map[string][]string{"core": {"api"}})
+		a.ExcludeDrafts = true
+		days, merged, err := a.GetAvgMergeTime(context.Background(), "api")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if days != 0.75 || merged != 2 {
+			t.Errorf("GetAvgMergeTime = %v days, %d PRs, want 0.75, 2", days, merged)
+		}
+	})
+
+	t.Run("timeline cut short by the request budget", func(t *testing.T) {
+		a := newFakeAnalyzer(&fakeProvider{repos: map[string]*fakeRepo{"api": repo}}, map[string][]string{"core": {"api"}})
+		a.ExcludeDrafts = true
+		a.MaxRequests = 2 // the PR search and listing, no timeline
+		if _, _, err := a.GetAvgMergeTime(context.Background(), "api"); !errors.Is(err, ErrRequestBudgetExceeded) {
+			t.Errorf("GetAvgMergeTime error = %v, want ErrRequestBudgetExceeded", err)
+		}
+	})
+}
+
+func TestGetPRThroughputCountsLongLivedPRs(t *testing.T) {
+	longLived := fakePR(3, -20) // opened before the period, merged in it
+	longLived.MergedAt = &github.Timestamp{Time: testStart.AddDate(0, 0, 3)}
+	longLived.ClosedAt, longLived.UpdatedAt = longLived.MergedAt, longLived.MergedAt
+	abandoned := fakePR(2, 5)
+	abandoned.MergedAt = nil
+	// newest update first, as listed for every basis but TimeBasisCreated
+	prs := []*github.PullRequest{fakePR(4, 20), abandoned, longLived, fakePR(1, -40)}
+
+	want := 2 / (testEnd.Sub(testStart).Hours() / (24 * 7)) // #4 and #3
+	for _, basis := range []TimeBasis{TimeBasisCreated, TimeBasisMerged} {
+		a := newFakeAnalyzer(&fakeProvider{repos: map[string]*fakeRepo{"api": {prs: prs}}}, map[string][]string{"core": {"api"}})
+		a.TimeBasis = basis
+		got, err := a.GetPRThroughput(context.Background(), "api")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("TimeBasis %s: GetPRThroughput = %v, want %v", basis.qualifier(), got, want)
+		}
+	}
+}
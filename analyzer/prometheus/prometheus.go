@@ -0,0 +1,111 @@
+// Package prometheus exposes collected RepoMetrics as Prometheus gauges.
+//
+// Typical wiring, e.g. after each cron run:
+//
+//	reg := prom.NewRegistry()
+//	if err := prometheus.RegisterMetrics(metrics, reg); err != nil {
+//		log.Fatal(err)
+//	}
+//	http.Handle("/metrics", prometheus.Handler(reg))
+//	log.Fatal(http.ListenAndServe(":9090", nil))
+package prometheus
+
+import (
+	"net/http"
+	"sync"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/raywall/using-gh-metrics/analyzer"
+)
+
+// gauge describes a single RepoMetrics field exported as a gauge.
+type gauge struct {
+	desc  *prom.Desc
+	value func(m analyzer.RepoMetrics) float64
+}
+
+var labels = []string{"repo", "area"}
+
+func newGauge(name, help string, value func(m analyzer.RepoMetrics) float64) gauge {
+	return gauge{desc: prom.NewDesc("github_repo_"+name, help, labels, nil), value: value}
+}
+
+var gauges = []gauge{
+	newGauge("unique_contributors", "Number of unique commit authors in the period.", func(m analyzer.RepoMetrics) float64 { return float64(m.UniqueContributors) }),
+	newGauge("conflict_rate", "Percentage of PRs with merge conflicts.", func(m analyzer.RepoMetrics) float64 { return m.ConflictRate }),
+	newGauge("conflict_merges_count", "Number of PRs with merge conflicts.", func(m analyzer.RepoMetrics) float64 { return float64(m.ConflictMergesCount) }),
+	newGauge("avg_merge_time_days", "Average time to merge a PR, in days.", func(m analyzer.RepoMetrics) float64 { return m.AvgMergeTimeDays }),
+	newGauge("merged_prs", "Number of merged PRs.", func(m analyzer.RepoMetrics) float64 { return float64(m.MergedPRs) }),
+	newGauge("avg_reviewers_per_pr", "Average number of reviewers per PR.", func(m analyzer.RepoMetrics) float64 { return m.AvgReviewersPerPR }),
+	newGauge("integration_issues", "Number of integration issues.", func(m analyzer.RepoMetrics) float64 { return float64(m.IntegrationIssues) }),
+	newGauge("revert_rate", "Percentage of revert commits.", func(m analyzer.RepoMetrics) float64 { return m.RevertRate }),
+	newGauge("main_branch_size_bytes", "Size of the default branch, in bytes.", func(m analyzer.RepoMetrics) float64 { return float64(m.MainBranchSizeBytes) }),
+	newGauge("main_file_count", "Number of files in the default branch.", func(m analyzer.RepoMetrics) float64 { return float64(m.MainFileCount) }),
+	newGauge("successful_reruns", "Number of workflow runs that succeeded on a re-run.", func(m analyzer.RepoMetrics) float64 { return float64(m.SuccessfulReruns) }),
+	newGauge("rollback_issues", "Number of rollback issues.", func(m analyzer.RepoMetrics) float64 { return float64(m.RollbackIssues) }),
+	newGauge("workflow_failures", "Number of failed workflow runs.", func(m analyzer.RepoMetrics) float64 { return float64(m.WorkflowFailures) }),
+	newGauge("successful_deploys", "Number of workflow runs that succeeded on the first attempt.", func(m analyzer.RepoMetrics) float64 { return float64(m.SuccessfulDeploys) }),
+	newGauge("deployments_per_day", "Deployment frequency, in deployments per day.", func(m analyzer.RepoMetrics) float64 { return m.DeploymentsPerDay }),
+	newGauge("lead_time_for_changes_hours", "Lead time for changes, in hours.", func(m analyzer.RepoMetrics) float64 { return m.LeadTimeForChangesHours }),
+	newGauge("change_failure_rate", "Percentage of deployments that failed or were rolled back.", func(m analyzer.RepoMetrics) float64 { return m.ChangeFailureRate }),
+	newGauge("mttr_hours", "Mean time to recovery from a rollback, in hours.", func(m analyzer.RepoMetrics) float64 { return m.MTTRHours }),
+	newGauge("avg_comments_per_item", "Average number of comments per issue or PR.", func(m analyzer.RepoMetrics) float64 { return m.AvgCommentsPerItem }),
+	newGauge("max_thread_depth", "Average length of the longest review thread per PR, in comments.", func(m analyzer.RepoMetrics) float64 { return m.MaxThreadDepth }),
+}
+
+// Collector is a prometheus.Collector serving the latest snapshot of RepoMetrics.
+type Collector struct {
+	mu      sync.RWMutex
+	metrics []analyzer.RepoMetrics
+}
+
+// NewCollector creates a Collector serving the given metrics.
+func NewCollector(metrics []analyzer.RepoMetrics) *Collector {
+	return &Collector{metrics: metrics}
+}
+
+// Update replaces the metrics served by the collector.
+func (c *Collector) Update(metrics []analyzer.RepoMetrics) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.metrics = metrics
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prom.Desc) {
+	for _, g := range gauges {
+		ch <- g.desc
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prom.Metric) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, m := range c.metrics {
+		for _, g := range gauges {
+			ch <- prom.MustNewConstMetric(g.desc, prom.GaugeValue, g.value(m), m.Repo, m.Area)
+		}
+	}
+}
+
+// RegisterMetrics exposes metrics on reg as github_repo_* gauges labeled by repo and area.
+// Calling it again with the same registerer replaces the previous snapshot instead of
+// registering duplicate series, so it can be called after every run.
+func RegisterMetrics(metrics []analyzer.RepoMetrics, reg prom.Registerer) error {
+	err := reg.Register(NewCollector(metrics))
+	if are, ok := err.(prom.AlreadyRegisteredError); ok {
+		if existing, ok := are.ExistingCollector.(*Collector); ok {
+			existing.Update(metrics)
+			return nil
+		}
+	}
+	return err
+}
+
+// Handler returns an http.Handler serving the metrics registered on reg, to be mounted on /metrics.
+func Handler(reg prom.Gatherer) http.Handler {
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,120 @@
+package analyzer
+
+import (
+	"context"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// Provider is the source of the data the metrics are computed from. Every REST request of the
+// Analyzer goes through it, so another forge (e.g. GitLab) can be supported by an implementation
+// translating its API into these types. The default implementation, NewGitHubProvider, calls the
+// GitHub REST API. UseGraphQL is GitHub-specific and only available with NewAnalyzerWithClient.
+type Provider interface {
+	GetAuthenticatedUser(ctx context.Context) (*github.User, *github.Response, error)
+	GetRepository(ctx context.Context, owner, repo string) (*github.Repository, *github.Response, error)
+	ListRepositoriesByOrg(ctx context.Context, org string, opts *github.RepositoryListByOrgOptions) ([]*github.Repository, *github.Response, error)
+
+	GetRef(ctx context.Context, owner, repo, ref string) (*github.Reference, *github.Response, error)
+	GetTree(ctx context.Context, owner, repo, sha string, recursive bool) (*github.Tree, *github.Response, error)
+	ListCommits(ctx context.Context, owner, repo string, opts *github.CommitsListOptions) ([]*github.RepositoryCommit, *github.Response, error)
+	GetCommit(ctx context.Context, owner, repo, sha string, opts *github.ListOptions) (*github.RepositoryCommit, *github.Response, error)
+	CompareCommits(ctx context.Context, owner, repo, base, head string, opts *github.ListOptions) (*github.CommitsComparison, *github.Response, error)
+
+	ListPullRequests(ctx context.Context, owner, repo string, opts *github.PullRequestListOptions) ([]*github.PullRequest, *github.Response, error)
+	GetPullRequest(ctx context.Context, owner, repo string, number int) (*github.PullRequest, *github.Response, error)
+	ListPullRequestsWithCommit(ctx context.Context, owner, repo, sha string, opts *github.ListOptions) ([]*github.PullRequest, *github.Response, error)
+	ListReviews(ctx context.Context, owner, repo string, number int, opts *github.ListOptions) ([]*github.PullRequestReview, *github.Response, error)
+	ListReviewComments(ctx context.Context, owner, repo string, number int, opts *github.PullRequestListCommentsOptions) ([]*github.PullRequestComment, *github.Response, error)
+	SearchIssues(ctx context.Context, query string, opts *github.SearchOptions) (*github.IssuesSearchResult, *github.Response, error)
+
+	ListIssues(ctx context.Context, owner, repo string, opts *github.IssueListByRepoOptions) ([]*github.Issue, *github.Response, error)
+	ListIssueComments(ctx context.Context, owner, repo string, number int, opts *github.IssueListCommentsOptions) ([]*github.IssueComment, *github.Response, error)
+	ListIssueTimeline(ctx context.Context, owner, repo string, number int, opts *github.ListOptions) ([]*github.Timeline, *github.Response, error)
+
+	ListWorkflows(ctx context.Context, owner, repo string, opts *github.ListOptions) (*github.Workflows, *github.Response, error)
+	ListWorkflowRuns(ctx context.Context, owner, repo string, workflowID int64, opts *github.ListWorkflowRunsOptions) (*github.WorkflowRuns, *github.Response, error)
+}
+
+// NewGitHubProvider returns the Provider backed by the GitHub REST API.
+func NewGitHubProvider(client *github.Client) Provider {
+	return githubProvider{client}
+}
+
+type githubProvider struct{ c *github.Client }
+
+func (p githubProvider) GetAuthenticatedUser(ctx context.Context) (*github.User, *github.Response, error) {
+	return p.c.Users.Get(ctx, "")
+}
+
+func (p githubProvider) GetRepository(ctx context.Context, owner, repo string) (*github.Repository, *github.Response, error) {
+	return p.c.Repositories.Get(ctx, owner, repo)
+}
+
+func (p githubProvider) ListRepositoriesByOrg(ctx context.Context, org string, opts *github.RepositoryListByOrgOptions) ([]*github.Repository, *github.Response, error) {
+	return p.c.Repositories.ListByOrg(ctx, org, opts)
+}
+
+func (p githubProvider) GetRef(ctx context.Context, owner, repo, ref string) (*github.Reference, *github.Response, error) {
+	return p.c.Git.GetRef(ctx, owner, repo, ref)
+}
+
+func (p githubProvider) GetTree(ctx context.Context, owner, repo, sha string, recursive bool) (*github.Tree, *github.Response, error) {
+	return p.c.Git.GetTree(ctx, owner, repo, sha, recursive)
+}
+
+func (p githubProvider) ListCommits(ctx context.Context, owner, repo string, opts *github.CommitsListOptions) ([]*github.RepositoryCommit, *github.Response, error) {
+	return p.c.Repositories.ListCommits(ctx, owner, repo, opts)
+}
+
+func (p githubProvider) GetCommit(ctx context.Context, owner, repo, sha string, opts *github.ListOptions) (*github.RepositoryCommit, *github.Response, error) {
+	return p.c.Repositories.GetCommit(ctx, owner, repo, sha, opts)
+}
+
+func (p githubProvider) CompareCommits(ctx context.Context, owner, repo, base, head string, opts *github.ListOptions) (*github.CommitsComparison, *github.Response, error) {
+	return p.c.Repositories.CompareCommits(ctx, owner, repo, base, head, opts)
+}
+
+func (p githubProvider) ListPullRequests(ctx context.Context, owner, repo string, opts *github.PullRequestListOptions) ([]*github.PullRequest, *github.Response, error) {
+	return p.c.PullRequests.List(ctx, owner, repo, opts)
+}
+
+func (p githubProvider) GetPullRequest(ctx context.Context, owner, repo string, number int) (*github.PullRequest, *github.Response, error) {
+	return p.c.PullRequests.Get(ctx, owner, repo, number)
+}
+
+func (p githubProvider) ListPullRequestsWithCommit(ctx context.Context, owner, repo, sha string, opts *github.ListOptions) ([]*github.PullRequest, *github.Response, error) {
+	return p.c.PullRequests.ListPullRequestsWithCommit(ctx, owner, repo, sha, opts)
+}
+
+func (p githubProvider) ListReviews(ctx context.Context, owner, repo string, number int, opts *github.ListOptions) ([]*github.PullRequestReview, *github.Response, error) {
+	return p.c.PullRequests.ListReviews(ctx, owner, repo, number, opts)
+}
+
+func (p githubProvider) ListReviewComments(ctx context.Context, owner, repo string, number int, opts *github.PullRequestListCommentsOptions) ([]*github.PullRequestComment, *github.Response, error) {
+	return p.c.PullRequests.ListComments(ctx, owner, repo, number, opts)
+}
+
+func (p githubProvider) SearchIssues(ctx context.Context, query string, opts *github.SearchOptions) (*github.IssuesSearchResult, *github.Response, error) {
+	return p.c.Search.Issues(ctx, query, opts)
+}
+
+func (p githubProvider) ListIssues(ctx context.Context, owner, repo string, opts *github.IssueListByRepoOptions) ([]*github.Issue, *github.Response, error) {
+	return p.c.Issues.ListByRepo(ctx, owner, repo, opts)
+}
+
+func (p githubProvider) ListIssueComments(ctx context.Context, owner, repo string, number int, opts *github.IssueListCommentsOptions) ([]*github.IssueComment, *github.Response, error) {
+	return p.c.Issues.ListComments(ctx, owner, repo, number, opts)
+}
+
+func (p githubProvider) ListIssueTimeline(ctx context.Context, owner, repo string, number int, opts *github.ListOptions) ([]*github.Timeline, *github.Response, error) {
+	return p.c.Issues.ListIssueTimeline(ctx, owner, repo, number, opts)
+}
+
+func (p githubProvider) ListWorkflows(ctx context.Context, owner, repo string, opts *github.ListOptions) (*github.Workflows, *github.Response, error) {
+	return p.c.Actions.ListWorkflows(ctx, owner, repo, opts)
+}
+
+func (p githubProvider) ListWorkflowRuns(ctx context.Context, owner, repo string, workflowID int64, opts *github.ListWorkflowRunsOptions) (*github.WorkflowRuns, *github.Response, error) {
+	return p.c.Actions.ListWorkflowRunsByID(ctx, owner, repo, workflowID, opts)
+}
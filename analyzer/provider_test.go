@@ -0,0 +1,362 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// fakeProvider is an in-memory Provider serving the fixtures of its repos (by name, whatever the owner)
+// and counting the calls of each method. Lists are served perPage items at a time, all at once when 0.
+// The fixtures must not be modified while requests are in flight.
+type fakeProvider struct {
+	repos   map[string]*fakeRepo
+	perPage int
+	fail    map[string]int // repo -> HTTP status failing the requests reading its fixtures; 403 is a rate limit error
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+// fakeRepo holds the data of a repo served by fakeProvider.
+type fakeRepo struct {
+	headSHA string // of the default branch
+	tree    []*github.TreeEntry
+	commits []*github.RepositoryCommit // newest first, as GitHub lists them
+	prs     []*github.PullRequest      // newest first
+	issues  []*github.Issue
+	// timelines by PR or issue number; when nil every timeline is empty, otherwise missing numbers are a 404
+	timelines map[int][]*github.Timeline
+	workflows []*github.Workflow // a single "CI" workflow (ci.yml) when nil
+}
+
+var _ Provider = (*fakeProvider)(nil)
+
+var (
+	testStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	testEnd   = time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// newFakeAnalyzer returns a quiet Analyzer over p for the January 2024 period.
+func newFakeAnalyzer(p *fakeProvider, projects map[string][]string) *Analyzer {
+	a := NewAnalyzerWithProvider(p, "acme", "main", "ci.yml", testStart, testEnd, projects)
+	a.Logger = nil
+	return a
+}
+
+// requests returns the total number of calls made to p.
+func (p *fakeProvider) requests() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := 0
+	for _, c := range p.calls {
+		n += c
+	}
+	return n
+}
+
+// callsTo returns the number of calls made to a method of p.
+func (p *fakeProvider) callsTo(method string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls[method]
+}
+
+func (p *fakeProvider) record(method string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.calls == nil {
+		p.calls = make(map[string]int)
+	}
+	p.calls[method]++
+}
+
+func (p *fakeProvider) repo(name string) (*fakeRepo, *github.Response, error) {
+	if status, ok := p.fail[name]; ok {
+		resp := fakeResponse(status, 0)
+		if status == http.StatusForbidden {
+			return nil, resp, &github.RateLimitError{Response: resp.Response, Message: "API rate limit exceeded"}
+		}
+		return nil, resp, &github.ErrorResponse{Response: resp.Response, Message: http.StatusText(status)}
+	}
+	if r, ok := p.repos[name]; ok {
+		return r, fakeResponse(http.StatusOK, 0), nil
+	}
+	resp := fakeResponse(http.StatusNotFound, 0)
+	return nil, resp, &github.ErrorResponse{Response: resp.Response, Message: "Not Found"}
+}
+
+// fakeResponse returns a response with plenty of rate limit left, so checkRateLimit never waits.
+func fakeResponse(status, nextPage int) *github.Response {
+	return &github.Response{
+		Response: &http.Response{StatusCode: status},
+		NextPage: nextPage,
+		Rate:     github.Rate{Limit: 5000, Remaining: 5000},
+	}
+}
+
+// fakePage returns the requested page (1-based, 0 for the first one) of items, copied so that callers
+// filtering in place don't touch the fixtures.
+func fakePage[T any](p *fakeProvider, items []T, page int) ([]T, *github.Response, error) {
+	if page < 1 {
+		page = 1
+	}
+	if p.perPage <= 0 {
+		return append([]T(nil), items...), fakeResponse(http.StatusOK, 0), nil
+	}
+	lo := min((page-1)*p.perPage, len(items))
+	hi := min(lo+p.perPage, len(items))
+	next := 0
+	if hi < len(items) {
+		next = page + 1
+	}
+	return append([]T(nil), items[lo:hi]...), fakeResponse(http.StatusOK, next), nil
+}
+
+func (p *fakeProvider) GetAuthenticatedUser(ctx context.Context) (*github.User, *github.Response, error) {
+	p.record("GetAuthenticatedUser")
+	return &github.User{Login: github.String("tester")}, fakeResponse(http.StatusOK, 0), nil
+}
+
+func (p *fakeProvider) GetRepository(ctx context.Context, owner, repo string) (*github.Repository, *github.Response, error) {
+	p.record("GetRepository")
+	if _, resp, err := p.repo(repo); err != nil {
+		return nil, resp, err
+	}
+	return &github.Repository{
+		Name:          github.String(repo),
+		Owner:         &github.User{Login: github.String(owner)},
+		DefaultBranch: github.String("main"),
+		Size:          github.Int(1),
+	}, fakeResponse(http.StatusOK, 0), nil
+}
+
+func (p *fakeProvider) ListRepositoriesByOrg(ctx context.Context, org string, opts *github.RepositoryListByOrgOptions) ([]*github.Repository, *github.Response, error) {
+	p.record("ListRepositoriesByOrg")
+	var repos []*github.Repository
+	for name := range p.repos {
+		repos = append(repos, &github.Repository{Name: github.String(name)})
+	}
+	return fakePage(p, repos, opts.Page)
+}
+
+func (p *fakeProvider) GetRef(ctx context.Context, owner, repo, ref string) (*github.Reference, *github.Response, error) {
+	p.record("GetRef")
+	r, resp, err := p.repo(repo)
+	if err != nil {
+		return nil, resp, err
+	}
+	return &github.Reference{Ref: github.String("refs/" + ref), Object: &github.GitObject{SHA: github.String(r.headSHA)}}, resp, nil
+}
+
+func (p *fakeProvider) GetTree(ctx context.Context, owner, repo, sha string, recursive bool) (*github.Tree, *github.Response, error) {
+	p.record("GetTree")
+	r, resp, err := p.repo(repo)
+	if err != nil {
+		return nil, resp, err
+	}
+	return &github.Tree{SHA: github.String(sha), Entries: r.tree}, resp, nil
+}
+
+func (p *fakeProvider) ListCommits(ctx context.Context, owner, repo string, opts *github.CommitsListOptions) ([]*github.RepositoryCommit, *github.Response, error) {
+	p.record("ListCommits")
+	r, resp, err := p.repo(repo)
+	if err != nil {
+		return nil, resp, err
+	}
+	var commits []*github.RepositoryCommit
+	for _, c := range r.commits {
+		date := c.GetCommit().GetAuthor().GetDate().Time
+		if (opts.Since.IsZero() || !date.Before(opts.Since)) && (opts.Until.IsZero() || !date.After(opts.Until)) {
+			commits = append(commits, c)
+		}
+	}
+	return fakePage(p, commits, opts.Page)
+}
+
+func (p *fakeProvider) GetCommit(ctx context.Context, owner, repo, sha string, opts *github.ListOptions) (*github.RepositoryCommit, *github.Response, error) {
+	p.record("GetCommit")
+	r, resp, err := p.repo(repo)
+	if err != nil {
+		return nil, resp, err
+	}
+	for _, c := range r.commits {
+		if c.GetSHA() == sha {
+			return c, resp, nil
+		}
+	}
+	resp = fakeResponse(http.StatusNotFound, 0)
+	return nil, resp, &github.ErrorResponse{Response: resp.Response, Message: "Not Found"}
+}
+
+func (p *fakeProvider) CompareCommits(ctx context.Context, owner, repo, base, head string, opts *github.ListOptions) (*github.CommitsComparison, *github.Response, error) {
+	p.record("CompareCommits")
+	return &github.CommitsComparison{}, fakeResponse(http.StatusOK, 0), nil
+}
+
+// ListPullRequests serves the PRs in the order of the fixture, expected to match opts.Sort and opts.Direction.
+func (p *fakeProvider) ListPullRequests(ctx context.Context, owner, repo string, opts *github.PullRequestListOptions) ([]*github.PullRequest, *github.Response, error) {
+	p.record("ListPullRequests")
+	r, resp, err := p.repo(repo)
+	if err != nil {
+		return nil, resp, err
+	}
+	var prs []*github.PullRequest
+	for _, pr := range r.prs {
+		if opts.State == "all" || opts.State == pr.GetState() {
+			prs = append(prs, pr)
+		}
+	}
+	return fakePage(p, prs, opts.Page)
+}
+
+func (p *fakeProvider) GetPullRequest(ctx context.Context, owner, repo string, number int) (*github.PullRequest, *github.Response, error) {
+	p.record("GetPullRequest")
+	r, resp, err := p.repo(repo)
+	if err != nil {
+		return nil, resp, err
+	}
+	for _, pr := range r.prs {
+		if pr.GetNumber() == number {
+			return pr, resp, nil
+		}
+	}
+	resp = fakeResponse(http.StatusNotFound, 0)
+	return nil, resp, &github.ErrorResponse{Response: resp.Response, Message: "Not Found"}
+}
+
+func (p *fakeProvider) ListPullRequestsWithCommit(ctx context.Context, owner, repo, sha string, opts *github.ListOptions) ([]*github.PullRequest, *github.Response, error) {
+	p.record("ListPullRequestsWithCommit")
+	return nil, fakeResponse(http.StatusOK, 0), nil
+}
+
+func (p *fakeProvider) ListReviews(ctx context.Context, owner, repo string, number int, opts *github.ListOptions) ([]*github.PullRequestReview, *github.Response, error) {
+	p.record("ListReviews")
+	return nil, fakeResponse(http.StatusOK, 0), nil
+}
+
+func (p *fakeProvider) ListReviewComments(ctx context.Context, owner, repo string, number int, opts *github.PullRequestListCommentsOptions) ([]*github.PullRequestComment, *github.Response, error) {
+	p.record("ListReviewComments")
+	return nil, fakeResponse(http.StatusOK, 0), nil
+}
+
+// SearchIssues always reports more matches than the Search API returns, so PRs are listed through
+// ListPullRequests and its fixtures.
+func (p *fakeProvider) SearchIssues(ctx context.Context, query string, opts *github.SearchOptions) (*github.IssuesSearchResult, *github.Response, error) {
+	p.record("SearchIssues")
+	return &github.IssuesSearchResult{Total: github.Int(searchResultCap + 1)}, fakeResponse(http.StatusOK, 0), nil
+}
+
+// ListIssues filters the issues by label and by update time, as GitHub does with opts.Since.
+func (p *fakeProvider) ListIssues(ctx context.Context, owner, repo string, opts *github.IssueListByRepoOptions) ([]*github.Issue, *github.Response, error) {
+	p.record("ListIssues")
+	r, resp, err := p.repo(repo)
+	if err != nil {
+		return nil, resp, err
+	}
+	var issues []*github.Issue
+	for _, i := range r.issues {
+		if !opts.Since.IsZero() && i.GetUpdatedAt().Before(opts.Since) {
+			continue
+		}
+		if len(opts.Labels) > 0 && !hasAllLabels(i, opts.Labels) {
+			continue
+		}
+		issues = append(issues, i)
+	}
+	return fakePage(p, issues, opts.Page)
+}
+
+func hasAllLabels(i *github.Issue, labels []string) bool {
+	for _, l := range labels {
+		if !hasLabel(i, []string{l}) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *fakeProvider) ListIssueComments(ctx context.Context, owner, repo string, number int, opts *github.IssueListCommentsOptions) ([]*github.IssueComment, *github.Response, error) {
+	p.record("ListIssueComments")
+	return nil, fakeResponse(http.StatusOK, 0), nil
+}
+
+func (p *fakeProvider) ListIssueTimeline(ctx context.Context, owner, repo string, number int, opts *github.ListOptions) ([]*github.Timeline, *github.Response, error) {
+	p.record("ListIssueTimeline")
+	r, resp, err := p.repo(repo)
+	if err != nil {
+		return nil, resp, err
+	}
+	events, ok := r.timelines[number]
+	if !ok && r.timelines != nil {
+		resp = fakeResponse(http.StatusNotFound, 0)
+		return nil, resp, &github.ErrorResponse{Response: resp.Response, Message: "Not Found"}
+	}
+	return fakePage(p, events, opts.Page)
+}
+
+func (p *fakeProvider) ListWorkflows(ctx context.Context, owner, repo string, opts *github.ListOptions) (*github.Workflows, *github.Response, error) {
+	p.record("ListWorkflows")
+	r, resp, err := p.repo(repo)
+	if err != nil {
+		return nil, resp, err
+	}
+	workflows := r.workflows
+	if workflows == nil {
+		workflows = []*github.Workflow{{ID: github.Int64(1), Name: github.String("CI"), Path: github.String(".github/workflows/ci.yml")}}
+	}
+	page, resp, err := fakePage(p, workflows, opts.Page)
+	return &github.Workflows{TotalCount: github.Int(len(workflows)), Workflows: page}, resp, err
+}
+
+func (p *fakeProvider) ListWorkflowRuns(ctx context.Context, owner, repo string, workflowID int64, opts *github.ListWorkflowRunsOptions) (*github.WorkflowRuns, *github.Response, error) {
+	p.record("ListWorkflowRuns")
+	return &github.WorkflowRuns{TotalCount: github.Int(0)}, fakeResponse(http.StatusOK, 0), nil
+}
+
+// fakeCommit returns a commit of the period by login (no linked account when empty) with the given parents.
+func fakeCommit(sha, login, email, msg string, day, parents int) *github.RepositoryCommit {
+	date := &github.Timestamp{Time: testStart.AddDate(0, 0, day)}
+	c := &github.RepositoryCommit{
+		SHA:    github.String(sha),
+		Commit: &github.Commit{Message: github.String(msg), Author: &github.CommitAuthor{Email: github.String(email), Date: date}},
+	}
+	if login != "" {
+		c.Author = &github.User{Login: github.String(login)}
+	}
+	for i := 0; i < parents; i++ {
+		c.Parents = append(c.Parents, &github.Commit{SHA: github.String(fmt.Sprintf("%s-parent%d", sha, i))})
+	}
+	return c
+}
+
+func TestFakeProviderGetUniqueContributors(t *testing.T) {
+	p := &fakeProvider{
+		perPage: 2, // three pages
+		repos: map[string]*fakeRepo{"api": {commits: []*github.RepositoryCommit{
+			fakeCommit("c5", "alice", "alice@example.com", "fix: again", 5, 1),
+			fakeCommit("c4", "bob", "bob@example.com", "feat: thing", 4, 1),
+			fakeCommit("c3", "alice", "alice@example.com", "fix: bug", 3, 1),
+			fakeCommit("c2", "dependabot", "bot@example.com", "chore: bump", 2, 1),
+			fakeCommit("c1", "carol", "carol@example.com", "docs: readme", -3, 1), // before the period
+		}}},
+	}
+	a := newFakeAnalyzer(p, map[string][]string{"core": {"api"}})
+	a.ExcludeBots = true
+
+	n, users, err := a.GetUniqueContributors(context.Background(), "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || len(users) != 2 {
+		t.Errorf("GetUniqueContributors = %d %v, want 2 contributors (alice, bob)", n, users)
+	}
+	if got := p.callsTo("ListCommits"); got != 2 {
+		t.Errorf("ListCommits called %d times, want 2 (4 commits in the period, 2 per page)", got)
+	}
+}
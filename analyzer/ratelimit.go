@@ -0,0 +1,82 @@
+package analyzer
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// RateLimitStats summarizes the API usage of a run.
+type RateLimitStats struct {
+	Requests      int           `json:"requests"`       // responses received, search included
+	Remaining     int           `json:"remaining"`      // core rate limit remaining at the last response
+	Limit         int           `json:"limit"`          // core rate limit per hour
+	Reset         time.Time     `json:"reset"`          // when the core rate limit resets
+	Sleeps        int           `json:"sleeps"`         // times we slept to spare the rate limit
+	SleepDuration time.Duration `json:"sleep_duration"` // total time slept
+}
+
+// rateTracker accumulates the RateLimitStats of the current run.
+type rateTracker struct {
+	mu     sync.Mutex
+	stats  RateLimitStats
+	warned bool
+}
+
+func (t *rateTracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stats = RateLimitStats{}
+	t.warned = false
+}
+
+func (t *rateTracker) record(resp *github.Response, search bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stats.Requests++
+	if !search {
+		t.stats.Remaining = resp.Rate.Remaining
+		t.stats.Limit = resp.Rate.Limit
+		t.stats.Reset = resp.Rate.Reset.Time
+	}
+}
+
+func (t *rateTracker) slept(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stats.Sleeps++
+	t.stats.SleepDuration += d
+}
+
+func (t *rateTracker) snapshot() RateLimitStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stats
+}
+
+// LastRateLimitStats returns the API usage of the last (or current) run of Check, CheckStream or CheckResumable.
+func (a *Analyzer) LastRateLimitStats() RateLimitStats {
+	return a.rateStats.snapshot()
+}
+
+// warnRateLimitBudget logs a warning, once per run, when the requests made per repo so far
+// project to more than the remaining rate limit before the pending repos are done.
+func (a *Analyzer) warnRateLimitBudget(done, pending int) {
+	t := a.rateStats
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.warned || done == 0 || pending == 0 || t.stats.Limit == 0 {
+		return
+	}
+	projected := t.stats.Requests / done * pending
+	if projected > t.stats.Remaining && time.Now().Before(t.stats.Reset) {
+		t.warned = true
+		a.logEvent(slog.LevelWarn, "rate limit budget may run out before the run finishes",
+			"remaining", t.stats.Remaining,
+			"projected_requests", projected,
+			"pending_repos", pending,
+			"reset", t.stats.Reset)
+	}
+}
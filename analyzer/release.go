@@ -0,0 +1,55 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// CheckBetweenTags computes all metrics of a repo for a release window instead of a calendar period:
+// from the commit of fromTag (excluded) to the commit of toTag (included), placed by committer date.
+// Everything else is as in CheckRepos, with the analyzer left on its own period. It fails with
+// ErrTagNotFound if either tag doesn't exist, and with the ValidatePeriod error if toTag isn't after fromTag.
+func (a *Analyzer) CheckBetweenTags(ctx context.Context, repo, fromTag, toTag string) (RepoMetrics, error) {
+	from, err := a.tagDate(ctx, repo, fromTag)
+	if err != nil {
+		return RepoMetrics{}, err
+	}
+	to, err := a.tagDate(ctx, repo, toTag)
+	if err != nil {
+		return RepoMetrics{}, err
+	}
+
+	// the period is exclusive at both ends and git dates have second resolution
+	b := a.withPeriod(from, to.Add(time.Second))
+	if err := b.ValidatePeriod(); err != nil {
+		return RepoMetrics{}, fmt.Errorf("tags %s..%s: %w", fromTag, toTag, err)
+	}
+	return b.checkRepo(ctx, repo), nil
+}
+
+// tagDate returns the committer date of the commit a tag points to.
+func (a *Analyzer) tagDate(ctx context.Context, repo, tag string) (time.Time, error) {
+	owner, name := a.splitRepo(repo)
+	commit, _, err := doRequest(ctx, a, func() (*github.RepositoryCommit, *github.Response, error) {
+		return a.provider.GetCommit(ctx, owner, name, "tags/"+tag, nil)
+	})
+	if err != nil {
+		var errResp *github.ErrorResponse
+		// GitHub answers 422 rather than 404 to a ref that resolves to no commit
+		if errors.As(err, &errResp) && errResp.Response != nil &&
+			(errResp.Response.StatusCode == http.StatusNotFound || errResp.Response.StatusCode == http.StatusUnprocessableEntity) {
+			return time.Time{}, fmt.Errorf("%s/%s: %w: %s: %w", owner, name, ErrTagNotFound, tag, err)
+		}
+		return time.Time{}, err
+	}
+	date := commit.GetCommit().GetCommitter().GetDate().Time
+	if date.IsZero() {
+		date = commit.GetCommit().GetAuthor().GetDate().Time
+	}
+	return date, nil
+}
@@ -0,0 +1,148 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+	"golang.org/x/sync/semaphore"
+)
+
+// ErrRequestBudgetExceeded is returned, instead of sending the request, once a run has made MaxRequests requests.
+var ErrRequestBudgetExceeded = errors.New("request budget exceeded")
+
+// doRequest sends one request through call. Every request of the package, REST or GraphQL (see gqlQuery),
+// goes through it: it's counted against MaxRequests, waits for a GlobalConcurrency slot and its response
+// feeds checkRateLimit.
+// Requests aren't sent once ctx is done. Outcomes feed the repo's breaker, if ctx carries one (see FailFastThreshold).
+// Requests hitting the secondary rate limit (403 with Retry-After, not reflected in resp.Rate) are
+// retried up to abuseRetries times after waiting for as long as GitHub asks. Errors are wrapped with
+// ErrRateLimited or ErrEmptyRepo when they belong to either class.
+func doRequest[T any](ctx context.Context, a *Analyzer, call func() (T, *github.Response, error)) (T, *github.Response, error) {
+	var zero T
+	for attempt := 0; ; attempt++ {
+		if ctx.Err() != nil {
+			return zero, nil, context.Cause(ctx)
+		}
+		if err := a.countRequest(); err != nil {
+			return zero, nil, err
+		}
+		release, err := a.acquireRequest(ctx)
+		if err != nil {
+			return zero, nil, context.Cause(ctx)
+		}
+		v, resp, err := call()
+		release()
+		a.checkRateLimit(resp)
+		if b, ok := ctx.Value(breakerKey{}).(*repoBreaker); ok {
+			b.record(resp, err)
+		}
+
+		var abuseErr *github.AbuseRateLimitError
+		if !errors.As(err, &abuseErr) || attempt >= abuseRetries {
+			return v, resp, classifyError(err)
+		}
+		wait := abuseErr.GetRetryAfter()
+		if wait <= 0 {
+			wait = abuseRetryDelay
+		}
+		a.logEvent(slog.LevelWarn, "secondary rate limit hit, retrying", "retry_after", wait, "attempt", attempt+1)
+		a.rateStats.slept(wait)
+		select {
+		case <-ctx.Done():
+			return zero, nil, context.Cause(ctx)
+		case <-time.After(wait):
+		}
+	}
+}
+
+const (
+	abuseRetries    = 3
+	abuseRetryDelay = time.Minute // when the response has no Retry-After
+)
+
+// repoBreaker aborts the metrics of a repo once FailFastThreshold requests in a row have failed, by canceling
+// the context they run with (cause ErrRepoAborted). Only failures pointing at an unhealthy API count: no response
+// at all, or a 5xx. Any other response, even a 404, shows the API is answering and resets the count.
+type repoBreaker struct {
+	threshold int32
+	failures  atomic.Int32
+	cancel    context.CancelCauseFunc
+}
+
+// breakerKey is the context key of the repoBreaker of a repo.
+type breakerKey struct{}
+
+// withBreaker returns a context carrying a new repoBreaker for a repo, canceled with cause ErrRepoAborted
+// once the breaker trips, and the func releasing it. Without FailFastThreshold, ctx is returned as is.
+func (a *Analyzer) withBreaker(ctx context.Context) (context.Context, func()) {
+	if a.FailFastThreshold <= 0 {
+		return ctx, func() {}
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	ctx = context.WithValue(ctx, breakerKey{}, &repoBreaker{threshold: int32(a.FailFastThreshold), cancel: cancel})
+	return ctx, func() { cancel(nil) }
+}
+
+// record counts the outcome of a request.
+func (b *repoBreaker) record(resp *github.Response, err error) {
+	if err == nil || (resp != nil && resp.StatusCode < http.StatusInternalServerError) {
+		b.failures.Store(0)
+		return
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+	if b.failures.Add(1) >= b.threshold {
+		b.cancel(ErrRepoAborted)
+	}
+}
+
+// countRequest counts a request about to be sent, or returns ErrRequestBudgetExceeded when MaxRequests
+// have already been sent in this run.
+func (a *Analyzer) countRequest() error {
+	for {
+		n := a.requests.Load()
+		if a.MaxRequests > 0 && n >= int64(a.MaxRequests) {
+			return ErrRequestBudgetExceeded
+		}
+		if a.requests.CompareAndSwap(n, n+1) {
+			return nil
+		}
+	}
+}
+
+// requestLimiter bounds the requests in flight across every repo, metric and goroutine of an Analyzer.
+// The semaphore is sized from GlobalConcurrency on first use.
+type requestLimiter struct {
+	once sync.Once
+	sem  *semaphore.Weighted // nil = unlimited
+}
+
+// acquireRequest waits until fewer than GlobalConcurrency requests are in flight and returns the func
+// that frees the slot once the request is done. It fails only if ctx is done first.
+func (a *Analyzer) acquireRequest(ctx context.Context) (release func(), err error) {
+	l := a.limiter
+	l.once.Do(func() {
+		if a.GlobalConcurrency > 0 {
+			l.sem = semaphore.NewWeighted(int64(a.GlobalConcurrency))
+		}
+	})
+	if l.sem == nil {
+		return func() {}, nil
+	}
+	if err := l.sem.Acquire(ctx, 1); err != nil {
+		return nil, err
+	}
+	return func() { l.sem.Release(1) }, nil
+}
+
+// RequestCount returns the number of API requests (REST and GraphQL) sent by the last (or current) run.
+func (a *Analyzer) RequestCount() int {
+	return int(a.requests.Load())
+}
@@ -0,0 +1,356 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// GetAvgTimeToFirstReview returns the average time between a PR being opened and its first response,
+// i.e. the earliest of its first submitted review and its first comment by someone other than the author.
+// PRs authored by bots (login ending in "[bot]") and PRs with no response at all are left out.
+func (a *Analyzer) GetAvgTimeToFirstReview(ctx context.Context, repo string) (time.Duration, error) {
+	allPRs, err := a.listPullRequests(ctx, repo, "all")
+	if err != nil {
+		return 0, err
+	}
+
+	var total time.Duration
+	count := 0
+	var mu sync.Mutex
+	g, gctx := a.innerGroup(ctx)
+	for _, pr := range allPRs {
+		author := pr.GetUser().GetLogin()
+		if isBot(author) {
+			continue
+		}
+		if gctx.Err() != nil {
+			break
+		}
+		g.Go(func() error {
+			var first time.Time
+			earliest := func(t time.Time) {
+				if !t.IsZero() && (first.IsZero() || t.Before(first)) {
+					first = t
+				}
+			}
+
+			reviews, err := a.listReviews(gctx, repo, pr.GetNumber())
+			if err != nil {
+				return hardError(err)
+			}
+			for _, r := range reviews {
+				if r.GetUser().GetLogin() != author {
+					earliest(r.GetSubmittedAt().Time)
+				}
+			}
+			comments, err := a.listIssueComments(gctx, repo, pr.GetNumber())
+			if err != nil {
+				return hardError(err)
+			}
+			for _, c := range comments {
+				if c.GetUser().GetLogin() != author {
+					earliest(c.GetCreatedAt().Time)
+				}
+			}
+
+			if first.IsZero() {
+				return nil
+			}
+			mu.Lock()
+			total += first.Sub(pr.GetCreatedAt().Time)
+			count++
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return 0, err
+	}
+
+	if count == 0 {
+		return 0, nil
+	}
+	return total / time.Duration(count), nil
+}
+
+// GetReviewOutcomeRates returns the percentage of reviews on PRs of the period that approved the PR and
+// that requested changes; the rest are plain comments. Each reviewer counts once per PR, with the state
+// of their last review, so a reviewer who requested changes and then approved is one approval.
+// Pending and dismissed reviews are left out.
+func (a *Analyzer) GetReviewOutcomeRates(ctx context.Context, repo string) (approveRate, changesRate float64, err error) {
+	allPRs, err := a.listPullRequests(ctx, repo, "all")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	approved, changes, total := 0, 0, 0
+	var mu sync.Mutex
+	g, gctx := a.innerGroup(ctx)
+	for _, pr := range allPRs {
+		if gctx.Err() != nil {
+			break
+		}
+		prNum := pr.GetNumber()
+		g.Go(func() error {
+			reviews, err := a.listReviews(gctx, repo, prNum)
+			if err != nil {
+				return hardError(err)
+			}
+			// Reviews come in chronological order, so the last one seen per reviewer wins
+			last := make(map[string]string)
+			for _, r := range reviews {
+				switch state := r.GetState(); state {
+				case "APPROVED", "CHANGES_REQUESTED", "COMMENTED":
+					last[r.GetUser().GetLogin()] = state
+				}
+			}
+			mu.Lock()
+			for _, state := range last {
+				total++
+				switch state {
+				case "APPROVED":
+					approved++
+				case "CHANGES_REQUESTED":
+					changes++
+				}
+			}
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return 0, 0, err
+	}
+
+	if total == 0 {
+		return 0, 0, nil
+	}
+	return float64(approved) / float64(total) * 100, float64(changes) / float64(total) * 100, nil
+}
+
+// GetReviewerLoad returns, per reviewer login, the number of PRs of the period they reviewed.
+// A reviewer counts once per PR however many reviews they left, and authors reviewing their own PR
+// aren't counted. The reviews are shared with GetAvgReviewersPerPR, so running both costs nothing extra.
+func (a *Analyzer) GetReviewerLoad(ctx context.Context, repo string) (map[string]int, error) {
+	allPRs, err := a.listPullRequests(ctx, repo, "all")
+	if err != nil {
+		return nil, err
+	}
+
+	load := make(map[string]int)
+	var mu sync.Mutex
+	g, gctx := a.innerGroup(ctx)
+	for _, pr := range allPRs {
+		if gctx.Err() != nil {
+			break
+		}
+		prNum, author := pr.GetNumber(), pr.GetUser().GetLogin()
+		g.Go(func() error {
+			reviews, err := a.listReviews(gctx, repo, prNum)
+			if err != nil {
+				return hardError(err)
+			}
+			reviewers := make(map[string]struct{})
+			for _, r := range reviews {
+				if login := r.GetUser().GetLogin(); login != "" && login != author {
+					reviewers[login] = struct{}{}
+				}
+			}
+			mu.Lock()
+			for login := range reviewers {
+				load[login]++
+			}
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return load, nil
+}
+
+// GetReviewerLatency returns, per reviewer login, the average time between their review being requested
+// and their first review of a PR of the period. Reviewers who were never requested (or whose request was
+// removed from the timeline) are timed from the PR creation. Bots and authors reviewing their own PR are left out.
+// It needs the timeline and the reviews of each PR, both shared with the other metrics of a run.
+func (a *Analyzer) GetReviewerLatency(ctx context.Context, repo string) (map[string]time.Duration, error) {
+	allPRs, err := a.listPullRequests(ctx, repo, "all")
+	if err != nil {
+		return nil, err
+	}
+
+	total := make(map[string]time.Duration)
+	count := make(map[string]int)
+	var mu sync.Mutex
+	g, gctx := a.innerGroup(ctx)
+	for _, pr := range allPRs {
+		if gctx.Err() != nil {
+			break
+		}
+		prNum, author, created := pr.GetNumber(), pr.GetUser().GetLogin(), pr.GetCreatedAt().Time
+		g.Go(func() error {
+			reviews, err := a.listReviews(gctx, repo, prNum)
+			if err != nil {
+				return hardError(err)
+			}
+			firstReview := make(map[string]time.Time)
+			for _, r := range reviews {
+				login, at := r.GetUser().GetLogin(), r.GetSubmittedAt().Time
+				if login == "" || login == author || isBot(login) || at.IsZero() {
+					continue
+				}
+				if first, ok := firstReview[login]; !ok || at.Before(first) {
+					firstReview[login] = at
+				}
+			}
+			if len(firstReview) == 0 {
+				return nil
+			}
+
+			events, err := a.listTimeline(gctx, repo, prNum)
+			if err != nil {
+				return hardError(err)
+			}
+			// the latest request before the review, so a reviewer added late isn't blamed for the wait before
+			requested := make(map[string]time.Time)
+			for _, e := range events {
+				login, at := e.GetReviewer().GetLogin(), e.GetCreatedAt().Time
+				reviewed, ok := firstReview[login]
+				if e.GetEvent() != "review_requested" || !ok || at.After(reviewed) {
+					continue
+				}
+				if at.After(requested[login]) {
+					requested[login] = at
+				}
+			}
+
+			mu.Lock()
+			for login, reviewed := range firstReview {
+				since, ok := requested[login]
+				if !ok {
+					since = created
+				}
+				total[login] += reviewed.Sub(since)
+				count[login]++
+			}
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	latency := make(map[string]time.Duration, len(total))
+	for login, sum := range total {
+		latency[login] = sum / time.Duration(count[login])
+	}
+	return latency, nil
+}
+
+// GetApprovedNotMergedCount returns the number of PRs of the period still open with at least one approval,
+// i.e. ready to go but not merged, and the average time they had been waiting at EndDate since their first
+// approval, in hours. Open means open at the time of the run; drafts and approvals after EndDate don't count.
+func (a *Analyzer) GetApprovedNotMergedCount(ctx context.Context, repo string) (count int, avgWaitHours float64, err error) {
+	allPRs, err := a.listPullRequests(ctx, repo, "all")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var totalWait time.Duration
+	var mu sync.Mutex
+	g, gctx := a.innerGroup(ctx)
+	for _, pr := range allPRs {
+		if pr.GetState() != "open" || pr.GetDraft() {
+			continue
+		}
+		if gctx.Err() != nil {
+			break
+		}
+		g.Go(func() error {
+			reviews, err := a.listReviews(gctx, repo, pr.GetNumber())
+			if err != nil {
+				return hardError(err)
+			}
+			var approved time.Time
+			for _, r := range reviews {
+				at := r.GetSubmittedAt().Time
+				if r.GetState() == "APPROVED" && at.Before(a.EndDate) && (approved.IsZero() || at.Before(approved)) {
+					approved = at
+				}
+			}
+			if approved.IsZero() {
+				return nil
+			}
+			mu.Lock()
+			totalWait += a.EndDate.Sub(approved)
+			count++
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return 0, 0, err
+	}
+	if count == 0 {
+		return 0, 0, nil
+	}
+	return count, totalWait.Hours() / float64(count), nil
+}
+
+// listReviews returns the reviews of a PR (first 100), shared by every review-based metric of a run.
+func (a *Analyzer) listReviews(ctx context.Context, repo string, number int) ([]*github.PullRequestReview, error) {
+	owner, name := a.splitRepo(repo)
+	return memoize(a.memo, a.memoKey(fmt.Sprintf("reviews#%d", number), repo), func() ([]*github.PullRequestReview, error) {
+		reviews, _, err := doRequest(ctx, a, func() ([]*github.PullRequestReview, *github.Response, error) {
+			return a.provider.ListReviews(ctx, owner, name, number, &github.ListOptions{PerPage: 100})
+		})
+		if err != nil {
+			return nil, err
+		}
+		return reviews, nil
+	})
+}
+
+// listIssueComments returns the conversation comments of an issue or PR (first 100), shared by the metrics of a run.
+func (a *Analyzer) listIssueComments(ctx context.Context, repo string, number int) ([]*github.IssueComment, error) {
+	owner, name := a.splitRepo(repo)
+	return memoize(a.memo, a.memoKey(fmt.Sprintf("issue-comments#%d", number), repo), func() ([]*github.IssueComment, error) {
+		comments, _, err := doRequest(ctx, a, func() ([]*github.IssueComment, *github.Response, error) {
+			return a.provider.ListIssueComments(ctx, owner, name, number, &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}})
+		})
+		if err != nil {
+			return nil, err
+		}
+		return comments, nil
+	})
+}
+
+// isBot reports whether a login belongs to a bot account.
+func isBot(login string) bool {
+	return strings.HasSuffix(login, "[bot]")
+}
+
+// excludedAuthor reports whether commits of login are left out of contributor metrics:
+// with ExcludeBots set, logins ending in "[bot]" or listed in BotLogins (case-insensitive) are.
+func (a *Analyzer) excludedAuthor(login string) bool {
+	if !a.ExcludeBots {
+		return false
+	}
+	if isBot(login) {
+		return true
+	}
+	for _, bot := range a.BotLogins {
+		if strings.EqualFold(login, bot) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package analyzer
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	_ "modernc.org/sqlite" // pure-Go driver, registered as "sqlite"
+)
+
+// sqliteSchema creates the metrics table, one row per repo per run. The most queried metrics get their
+// own column; the full RepoMetrics is kept as JSON in data, so metrics added later aren't lost.
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS metrics (
+	id                          INTEGER PRIMARY KEY AUTOINCREMENT,
+	run_date                    TEXT NOT NULL,
+	owner                       TEXT NOT NULL,
+	repo                        TEXT NOT NULL,
+	area                        TEXT NOT NULL,
+	period_from                 TEXT NOT NULL,
+	period_to                   TEXT NOT NULL,
+	skipped                     INTEGER NOT NULL,
+	unique_contributors         INTEGER NOT NULL,
+	merged_prs                  INTEGER NOT NULL,
+	avg_merge_time_days         REAL NOT NULL,
+	conflict_rate               REAL NOT NULL,
+	revert_rate                 REAL NOT NULL,
+	workflow_failures           INTEGER NOT NULL,
+	successful_deploys          INTEGER NOT NULL,
+	deployments_per_day         REAL NOT NULL,
+	lead_time_for_changes_hours REAL NOT NULL,
+	change_failure_rate         REAL NOT NULL,
+	mttr_hours                  REAL NOT NULL,
+	data                        TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS metrics_repo_run_date ON metrics (owner, repo, run_date);`
+
+// ExportSQLite appends the metrics to the metrics table of the SQLite database at dbPath, one row per repo
+// stamped with the current run_date (RFC 3339, UTC), so scheduled runs build up a queryable time series.
+// The database and table are created if missing; existing rows are never touched.
+// All rows of a call are inserted in a single transaction.
+func (a *Analyzer) ExportSQLite(metrics []RepoMetrics, dbPath string) error {
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT INTO metrics (
+		run_date, owner, repo, area, period_from, period_to, skipped,
+		unique_contributors, merged_prs, avg_merge_time_days, conflict_rate, revert_rate,
+		workflow_failures, successful_deploys, deployments_per_day, lead_time_for_changes_hours,
+		change_failure_rate, mttr_hours, data
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	runDate := time.Now().UTC().Format(time.RFC3339)
+	for _, m := range metrics {
+		data, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		_, err = stmt.Exec(
+			runDate, m.Owner, m.Repo, m.Area, m.PeriodFrom, m.PeriodTo, m.Skipped,
+			m.UniqueContributors, m.MergedPRs, m.AvgMergeTimeDays, m.ConflictRate, m.RevertRate,
+			m.WorkflowFailures, m.SuccessfulDeploys, m.DeploymentsPerDay, m.LeadTimeForChangesHours,
+			m.ChangeFailureRate, m.MTTRHours, string(data),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,53 @@
+package analyzer
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// TokenConfig lists the places ResolveToken looks for a GitHub token.
+type TokenConfig struct {
+	Token     string // Explicit token, e.g. from a config; avoid passing it on the command line, where ps and shell history expose it
+	TokenFile string // File holding the token; surrounding whitespace is trimmed
+	EnvVar    string // Environment variable holding the token; defaults to GITHUB_TOKEN
+}
+
+// ErrNoToken is returned by ResolveToken when no token was found anywhere.
+var ErrNoToken = errors.New("no GitHub token found")
+
+// ResolveToken returns the first token found, trying in order: cfg.Token, cfg.TokenFile, the cfg.EnvVar
+// environment variable and the credentials stored by the GitHub CLI (`gh auth token`).
+// The token value is never included in the returned errors.
+func ResolveToken(cfg TokenConfig) (string, error) {
+	if cfg.Token != "" {
+		return cfg.Token, nil
+	}
+
+	if cfg.TokenFile != "" {
+		data, err := os.ReadFile(cfg.TokenFile)
+		if err != nil {
+			return "", err
+		}
+		if token := strings.TrimSpace(string(data)); token != "" {
+			return token, nil
+		}
+	}
+
+	envVar := cfg.EnvVar
+	if envVar == "" {
+		envVar = "GITHUB_TOKEN"
+	}
+	if token := os.Getenv(envVar); token != "" {
+		return token, nil
+	}
+
+	if out, err := exec.Command("gh", "auth", "token").Output(); err == nil {
+		if token := strings.TrimSpace(string(out)); token != "" {
+			return token, nil
+		}
+	}
+
+	return "", ErrNoToken
+}
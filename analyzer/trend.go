@@ -0,0 +1,111 @@
+package analyzer
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// trendFlatThreshold is the slope, relative to the mean of a smoothed series, under which
+// the series counts as flat: less than 1% of its typical value per period.
+const trendFlatThreshold = 0.01
+
+// MetricTrend is the evolution of a scalar metric of a repo over several runs, oldest first.
+type MetricTrend struct {
+	Values   []float64 `json:"values"`
+	Smoothed []float64 `json:"smoothed"` // SmoothSeries of Values
+	// Slope is the least-squares slope of Smoothed, in metric units per period.
+	Slope float64 `json:"slope"`
+	// Direction is "up", "down" or "flat" (a slope under 1% of the mean per period). Whether up is good
+	// depends on the metric: it's bad news for avg_merge_time_days, good news for merged_prs.
+	Direction string `json:"direction"`
+}
+
+// SmoothSeries returns the exponential moving average of values: each point is alpha times the value
+// plus 1-alpha times the previous point, starting from the first value. A lower alpha smooths more;
+// alpha outside (0, 1] is taken as 1, i.e. no smoothing.
+func SmoothSeries(values []float64, alpha float64) []float64 {
+	if alpha <= 0 || alpha > 1 {
+		alpha = 1
+	}
+	smoothed := make([]float64, len(values))
+	for i, v := range values {
+		if i == 0 {
+			smoothed[i] = v
+			continue
+		}
+		smoothed[i] = alpha*v + (1-alpha)*smoothed[i-1]
+	}
+	return smoothed
+}
+
+// Trend returns, per repo, the trend of every scalar metric keyed by its JSON name (e.g. "avg_merge_time_days"),
+// from the metrics of several runs (e.g. read back from the JSON exports of past months), smoothed with alpha
+// (see SmoothSeries). Runs are ordered by the start of their period, whatever order they're given in.
+func Trend(history []RepoMetrics, alpha float64) map[string]map[string]MetricTrend {
+	byRepo := make(map[string][]RepoMetrics)
+	for _, m := range history {
+		byRepo[m.Repo] = append(byRepo[m.Repo], m)
+	}
+
+	trends := make(map[string]map[string]MetricTrend)
+	for repo, runs := range byRepo {
+		sort.SliceStable(runs, func(i, j int) bool { return periodStart(runs[i]).Before(periodStart(runs[j])) })
+
+		series := make(map[string][]float64)
+		for _, m := range runs {
+			for name, v := range scalarMetrics(m) {
+				series[name] = append(series[name], v)
+			}
+		}
+		repoTrends := make(map[string]MetricTrend)
+		for name, values := range series {
+			smoothed := SmoothSeries(values, alpha)
+			slope := slopeOf(smoothed)
+			repoTrends[name] = MetricTrend{Values: values, Smoothed: smoothed, Slope: slope, Direction: direction(slope, smoothed)}
+		}
+		trends[repo] = repoTrends
+	}
+	return trends
+}
+
+// periodStart returns the start of the period of m; an unparsable PeriodFrom sorts first.
+func periodStart(m RepoMetrics) time.Time {
+	t, _ := time.Parse("02-01-2006", m.PeriodFrom)
+	return t
+}
+
+// slopeOf returns the least-squares slope of values against their index, 0 for fewer than two values.
+func slopeOf(values []float64) float64 {
+	n := float64(len(values))
+	if n < 2 {
+		return 0
+	}
+	var sumX, sumY, sumXY, sumXX float64
+	for i, y := range values {
+		x := float64(i)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	return (n*sumXY - sumX*sumY) / (n*sumXX - sumX*sumX)
+}
+
+// direction classifies a slope as "up", "down" or "flat", relative to the mean magnitude of the series.
+func direction(slope float64, values []float64) string {
+	var mean float64
+	for _, v := range values {
+		mean += math.Abs(v)
+	}
+	if len(values) > 0 {
+		mean /= float64(len(values))
+	}
+	switch {
+	case slope == 0 || (mean > 0 && math.Abs(slope)/mean < trendFlatThreshold):
+		return "flat"
+	case slope > 0:
+		return "up"
+	}
+	return "down"
+}
@@ -1,43 +1,227 @@
 package analyzer
 
 import (
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/google/go-github/v62/github"
+	"github.com/shurcooL/githubv4"
 )
 
 // RepoMetrics holds all the computed metrics for a single repository.
 type RepoMetrics struct {
-	Repo                string         `json:"repo"`
-	UniqueContributors  int            `json:"unique_contributors"`
-	ContributorsList    []string       `json:"contributors_list"`
-	CommitDist          map[string]int `json:"commit_dist"`
-	ConflictRate        float64        `json:"conflict_rate"`
-	AvgMergeTimeDays    float64        `json:"avg_merge_time_days"`
-	AvgReviewersPerPR   float64        `json:"avg_reviewers_per_pr"`
-	CrossTeamReviews    int            `json:"cross_team_reviews"`
-	ChurnByFile         map[string]int `json:"churn_by_file"`
-	ChurnByDir          map[string]int `json:"churn_by_dir"`
-	IntegrationIssues   int            `json:"integration_issues"`
-	RevertRate          float64        `json:"revert_rate"`
-	MainBranchSizeBytes int64          `json:"main_branch_size_bytes"`
-	MainFileCount       int            `json:"main_file_count"`
-	SuccessfulReruns    int            `json:"successful_reruns"`
-	ConflictMergesCount int            `json:"conflict_merges_count"`
-	RollbackIssues      int            `json:"rollback_issues"`
-	WorkflowFailures    int            `json:"workflow_failures"`
-	SuccessfulDeploys   int            `json:"successful_deploys"`
-	AvgThreadDepth      float64        `json:"avg_thread_depth"`
+	Repo                       string             `json:"repo"`
+	Owner                      string             `json:"owner"`
+	Area                       string             `json:"area"`
+	PeriodFrom                 string             `json:"period_from"` // dd-mm-yyyy
+	PeriodTo                   string             `json:"period_to"`   // dd-mm-yyyy
+	Skipped                    bool               `json:"skipped,omitempty"`
+	SkipReason                 string             `json:"skip_reason,omitempty"` // "archived", "empty" or "unauthorized"
+	Failed                     bool               `json:"failed,omitempty"`      // Aborted after FailFastThreshold consecutive API failures; the metrics left undone are in Errors
+	IsFork                     bool               `json:"is_fork"`               // Commit metrics of forks include commits synced from upstream unless TreatForksSpecially is set
+	Errors                     map[string]string  `json:"errors,omitempty"`      // Key: metric (e.g. "AvgMergeTime"), Value: why it failed
+	UniqueContributors         int                `json:"unique_contributors"`
+	ContributorsList           []string           `json:"contributors_list"`
+	CommitDist                 map[string]int     `json:"commit_dist"`
+	CommitTypeDist             map[string]int     `json:"commit_type_dist"`
+	CommitActivityHeatmap      map[string]int     `json:"commit_activity_heatmap"` // Key: "Mon-14" style weekday-hour bucket
+	BusFactor                  int                `json:"bus_factor"`
+	UnlinkedCommits            int                `json:"unlinked_commits"`
+	UnlinkedCommitsByEmail     map[string]int     `json:"unlinked_commits_by_email"`
+	DirectPushCount            int                `json:"direct_push_count"`
+	ConflictRate               float64            `json:"conflict_rate"`
+	AvgMergeTimeDays           float64            `json:"avg_merge_time_days"`
+	MergeTimeP50Days           float64            `json:"merge_time_p50_days"`
+	MergeTimeP90Days           float64            `json:"merge_time_p90_days"`
+	MergedPRs                  int                `json:"merged_prs"`
+	ClosedUnmergedPRs          int                `json:"closed_unmerged_prs"`
+	PRsMergedPerWeek           float64            `json:"prs_merged_per_week"`
+	StalePRCount               int                `json:"stale_pr_count"`
+	ApprovedNotMergedCount     int                `json:"approved_not_merged_count"`
+	AvgWaitAfterApprovalHours  float64            `json:"avg_wait_after_approval_hours"`
+	AvgReviewersPerPR          float64            `json:"avg_reviewers_per_pr"`
+	CrossTeamReviews           int                `json:"cross_team_reviews"`
+	ReviewerLoad               map[string]int     `json:"reviewer_load"`    // Key: reviewer login, Value: PRs reviewed
+	ReviewerLatency            map[string]float64 `json:"reviewer_latency"` // Key: reviewer login, Value: average hours from review request to review
+	ApprovalRate               float64            `json:"approval_rate"`
+	ChangesRequestedRate       float64            `json:"changes_requested_rate"`
+	ThreadResolutionRate       float64            `json:"thread_resolution_rate"`
+	AvgPRChangedFiles          float64            `json:"avg_pr_changed_files"`
+	AvgPRLinesChanged          float64            `json:"avg_pr_lines_changed"`
+	PRLinesChangedP90          float64            `json:"pr_lines_changed_p90"`
+	UnreviewedMerges           int                `json:"unreviewed_merges"`
+	SelfMerges                 int                `json:"self_merges"`
+	MergeMethodDist            map[string]int     `json:"merge_method_dist"` // Key: "merge", "squash", "rebase" or "unknown"
+	AvgTimeToFirstReviewHours  float64            `json:"avg_time_to_first_review_hours"`
+	ChurnByFile                map[string]int     `json:"churn_by_file"`
+	ChurnByDir                 map[string]int     `json:"churn_by_dir"`
+	ChurnByExtension           map[string]int     `json:"churn_by_extension"`
+	LineChurnByFile            map[string]int     `json:"line_churn_by_file"`
+	TotalAdditions             int                `json:"total_additions"`
+	TotalDeletions             int                `json:"total_deletions"`
+	IntegrationIssues          int                `json:"integration_issues"`
+	IssueCountsByLabel         map[string]int     `json:"issue_counts_by_label,omitempty"`
+	IssuesOpened               int                `json:"issues_opened"`
+	IssuesClosed               int                `json:"issues_closed"`
+	AvgIssueResolutionHours    float64            `json:"avg_issue_resolution_hours"`
+	RevertRate                 float64            `json:"revert_rate"`
+	MainBranchSizeBytes        int64              `json:"main_branch_size_bytes"`
+	MainFileCount              int                `json:"main_file_count"`
+	SuccessfulReruns           int                `json:"successful_reruns"`
+	ConflictMergesCount        int                `json:"conflict_merges_count"`
+	ResolvedConflicts          int                `json:"resolved_conflicts"`
+	RollbackIssues             int                `json:"rollback_issues"`
+	WorkflowFailures           int                `json:"workflow_failures"`
+	WorkflowFailuresByName     map[string]int     `json:"workflow_failures_by_name"` // Key: workflow as configured in Workflows
+	SuccessfulDeploys          int                `json:"successful_deploys"`
+	SuccessfulDeploysByName    map[string]int     `json:"successful_deploys_by_name"`
+	DeploymentsPerDay          float64            `json:"deployments_per_day"`
+	LeadTimeForChangesHours    float64            `json:"lead_time_for_changes_hours"`
+	ChangeFailureRate          float64            `json:"change_failure_rate"`
+	MTTRHours                  float64            `json:"mttr_hours"`
+	AvgWorkflowDurationSeconds int                `json:"avg_workflow_duration_seconds"`
+	AvgWorkflowDuration        string             `json:"avg_workflow_duration"`       // hh:mm:ss
+	WorkflowDurationHistogram  map[string]int     `json:"workflow_duration_histogram"` // Key: "0-2m", "2-5m", "5-10m" or "10m+"
+	AvgCommentsPerItem         float64            `json:"avg_comments_per_item"`
+	MaxThreadDepth             float64            `json:"max_thread_depth"` // Mean over PRs of the longest review thread, in comments (UseGraphQL only)
+	Details                    *RepoDetails       `json:"-"`                // Collected with CollectDetails and written apart by ExportDetails
+
+	// interrupted is set when a metric was cut short by the run rather than by the repo (see interruptedBy),
+	// so CheckResumable doesn't keep the result. Only the error text survives in Errors.
+	interrupted bool
+}
+
+// AreaSummary holds the metrics of all repositories of an area rolled up into a single record.
+// Counters are summed. AvgMergeTimeDays is weighted by the number of merged PRs of each repo,
+// so busy repos weigh more; rates and per-PR averages are a plain mean of the repo values.
+type AreaSummary struct {
+	Area                string  `json:"area"`
+	Repos               int     `json:"repos"`
+	UniqueContributors  int     `json:"unique_contributors"`
+	AvgConflictRate     float64 `json:"avg_conflict_rate"`
+	ConflictMergesCount int     `json:"conflict_merges_count"`
+	MergedPRs           int     `json:"merged_prs"`
+	AvgMergeTimeDays    float64 `json:"avg_merge_time_days"`
+	AvgReviewersPerPR   float64 `json:"avg_reviewers_per_pr"`
+	IntegrationIssues   int     `json:"integration_issues"`
+	AvgRevertRate       float64 `json:"avg_revert_rate"`
+	MainBranchSizeBytes int64   `json:"main_branch_size_bytes"`
+	MainFileCount       int     `json:"main_file_count"`
+	SuccessfulReruns    int     `json:"successful_reruns"`
+	RollbackIssues      int     `json:"rollback_issues"`
+	WorkflowFailures    int     `json:"workflow_failures"`
+	SuccessfulDeploys   int     `json:"successful_deploys"`
+	AvgCommentsPerItem  float64 `json:"avg_comments_per_item"`
+}
+
+// FileChurn is the churn of a single file, as returned by TopChurn.
+type FileChurn struct {
+	Path  string `json:"path"`
+	Count int    `json:"count"`
 }
 
 // Analyzer is the main struct for GitHub metrics analysis.
 type Analyzer struct {
-	Owner         string
-	DefaultBranch string
-	WorkflowID    string // Can be name or ID; we'll assume string ID and parse to int64
-	StartDate     time.Time
-	EndDate       time.Time
-	Token         string
-	Projects      map[string][]string // Key: area/product, Value: []repos
-	client        *github.Client
+	Owner                    string
+	DefaultBranch            string
+	BaseBranchFilter         string   // Base branch of the PRs counted by PR-based metrics; NewAnalyzer defaults it to DefaultBranch, empty = all branches
+	PRLabelFilter            []string // Restrict PR-based metrics to PRs carrying at least one of these labels, matched case-insensitively (none = all PRs)
+	WorkflowID               string   // Numeric ID, workflow name or workflow file (e.g. "deploy.yml")
+	Workflows                []string // Workflows broken down in the *ByName action metrics (IDs, names or files); empty = just WorkflowID
+	StartDate                time.Time
+	EndDate                  time.Time
+	TimeBasis                TimeBasis // Timestamp that places PRs and issues in the period (default TimeBasisCreated)
+	Token                    string
+	Projects                 map[string][]string                // Key: area/product, Value: []repos ("repo" under Owner, or "owner/repo")
+	MTTRIncludeOpen          bool                               // Count still-open rollback issues as recovered at EndDate in GetMTTR
+	RollbackLabels           []string                           // Issue labels that mark a rollback, matched case-insensitively
+	IntegrationLabels        []string                           // Issue labels that mark an integration bug, matched case-insensitively
+	TrackedLabels            []string                           // Labels broken down in RepoMetrics.IssueCountsByLabel (none = disabled)
+	IncludePRsInIssueMetrics bool                               // Count PRs carrying the labels as issues too (the issues endpoint returns both)
+	Cache                    Cache                              // Caches results derived from immutable data (e.g. git trees); nil disables caching
+	RepoConcurrency          int                                // Number of repos processed concurrently by Check
+	InnerConcurrency         int                                // Per-item requests (one per PR, commit, issue...) in flight per metric
+	GlobalConcurrency        int                                // Requests in flight at once across all repos and metrics (0 = unlimited); read on the first request
+	UseGraphQL               bool                               // Fetch PRs with reviews and mergeable state in bulk via GraphQL instead of one REST call per PR; also enables the GraphQL-only metrics in Check
+	DetectRevertPRs          bool                               // Also count merged PRs titled "Revert ..." in GetRevertRate
+	MaxPages                 int                                // Maximum pages fetched per paginated listing (0 = unlimited)
+	MaxRequests              int                                // Maximum API requests per run; once reached, requests fail with ErrRequestBudgetExceeded (0 = unlimited)
+	FailFastThreshold        int                                // Consecutive API failures (no response or 5xx) after which the rest of a repo is aborted and it is marked Failed (0 = never)
+	EnabledMetrics           map[string]bool                    // Key: metric name (e.g. "ChurnByFile"); false skips it in Check, absent metrics run
+	StaleThreshold           time.Duration                      // Age after which an open PR counts as stale
+	CountDrafts              bool                               // Count draft PRs in GetStalePRCount
+	ExcludeDrafts            bool                               // Leave draft PRs out of PR-based metrics and measure merge time from ready-for-review
+	ExcludeBots              bool                               // Leave bot authors out of contributor and commit distribution metrics
+	ExcludeMergeCommits      bool                               // Leave merge commits out of every commit-based metric (contributors, distribution, revert rate, churn...)
+	TreatForksSpecially      bool                               // Count only the commits of a fork that aren't on its parent's default branch (one compare request per fork)
+	BotLogins                []string                           // Extra bot logins for ExcludeBots, besides any login ending in "[bot]"
+	BusFactorThreshold       float64                            // Share of commits (0-1, exclusive) the top contributors must exceed in GetBusFactor; default 0.5
+	AttributeByEmail         bool                               // Count commits without a linked GitHub account under their git e-mail in GetCommitDistribution
+	TopChurnFiles            int                                // Most churned files listed per repo by ExportMarkdown and ExportHTML (default 20); Export keeps them all
+	ChurnIncludeGlobs        []string                           // path.Match patterns of the files counted by the churn metrics, e.g. "src" or "*.go" (none = all files)
+	ChurnExcludeGlobs        []string                           // path.Match patterns of files left out of the churn metrics, e.g. "vendor", "dist", "*.pb.go"
+	Timezone                 *time.Location                     // Timezone of the day-hour buckets of GetCommitActivityHeatmap and the workflow run window (nil = UTC)
+	OutputDir                string                             // Directory where exports with a relative filename are written (created if missing)
+	Logger                   Logger                             // Receives the events of a run (repo done, rate limit warnings...); nil disables logging
+	ProgressFunc             func(done, total int, repo string) // Called as each repo completes in Check/CheckStream; calls are serialized
+	CollectDetails           bool                               // Attach the PRs, commits and workflow runs behind the metrics to RepoMetrics.Details (see ExportDetails)
+	provider                 Provider
+	gql                      *githubv4.Client
+	workflowIDs              *sync.Map // Key: "repo#workflow", Value: resolved workflow ID (int64)
+	memo                     *memo     // List results shared by the metrics of a run
+	rateStats                *rateTracker
+	requests                 *atomic.Int64   // Requests sent in the current run, see MaxRequests
+	limiter                  *requestLimiter // Enforces GlobalConcurrency
+	areaIndex                *areaIndex      // Repo to area, see AreaForRepo
+	repoScoped               bool            // The token is granted per repo (fine-grained PAT or GitHub App), so a 404 may only mean no access
+}
+
+// Version is the version of the tool, recorded in every report written by ExportV2.
+const Version = "0.2.0"
+
+// SchemaVersion is the version of the Report format. Bump the minor for added fields
+// and the major for renamed or removed ones.
+const SchemaVersion = "2.0"
+
+// Report is the envelope written by ExportV2.
+type Report struct {
+	SchemaVersion string        `json:"schema_version"`
+	GeneratedAt   time.Time     `json:"generated_at"`
+	ToolVersion   string        `json:"tool_version"`
+	Owner         string        `json:"owner"`
+	Period        ReportPeriod  `json:"period"`
+	Repos         []RepoMetrics `json:"repos"`
+}
+
+// ReportPeriod is the analyzed period of a Report.
+type ReportPeriod struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// TimeBasis selects which timestamp places a PR or issue in the analyzed period.
+// It's honored by every metric built on the PR listing (merge time, reviews, conflicts, reverts,
+// comments per item...) and on labeled issues (rollback, integration, per-label counts).
+// Commits are always placed by author date, workflow runs by creation, GetStalePRCount looks at PRs
+// open at EndDate and GetIssueThroughput has its own opened/closed semantics.
+type TimeBasis int
+
+const (
+	TimeBasisCreated TimeBasis = iota // Created in the period (default)
+	TimeBasisUpdated                  // Last updated in the period
+	TimeBasisMerged                   // Merged in the period; only merged PRs count, and issues use TimeBasisClosed
+	TimeBasisClosed                   // Closed (merged or not) in the period
+)
+
+// qualifier returns the Search API qualifier of the basis.
+func (b TimeBasis) qualifier() string {
+	switch b {
+	case TimeBasisUpdated:
+		return "updated"
+	case TimeBasisMerged:
+		return "merged"
+	case TimeBasisClosed:
+		return "closed"
+	}
+	return "created"
 }
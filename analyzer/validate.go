@@ -0,0 +1,131 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// Validate checks the configuration without computing any metric: settings must be in range, the token must be valid,
+// every repo in Projects must exist and be visible to it (see ErrUnauthorizedRepo), and WorkflowID and every workflow
+// of Workflows must resolve in each of them.
+// The token's OAuth scopes are checked too: metrics it can't compute are disabled (see checkScopes).
+// All problems found are returned together, joined with errors.Join.
+func (a *Analyzer) Validate(ctx context.Context) error {
+	return a.ValidateRepos(ctx, a.allRepos())
+}
+
+// ValidateRepos checks the configuration like Validate, but only looks up the given repos (e.g. the ones
+// CheckRepos will analyze), so a repo of Projects left out of the run can't fail its validation.
+func (a *Analyzer) ValidateRepos(ctx context.Context, repos []string) error {
+	var errs []error
+
+	if a.InnerConcurrency < 1 {
+		errs = append(errs, fmt.Errorf("InnerConcurrency must be >= 1, got %d", a.InnerConcurrency))
+	}
+	if err := a.ValidatePeriod(); err != nil {
+		errs = append(errs, err)
+	}
+	for _, pattern := range append(slices.Clone(a.ChurnIncludeGlobs), a.ChurnExcludeGlobs...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("invalid churn glob %q: %w", pattern, err))
+		}
+	}
+
+	_, resp, err := doRequest(ctx, a, func() (*github.User, *github.Response, error) {
+		return a.provider.GetAuthenticatedUser(ctx)
+	})
+	if err != nil {
+		return errors.Join(append(errs, fmt.Errorf("invalid token: %w", err))...)
+	}
+
+	hasPrivate := false
+	for _, repo := range repos {
+		owner, name := a.splitRepo(repo)
+		info, err := a.getRepository(ctx, repo)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("repo %s/%s: %w", owner, name, err))
+			continue
+		}
+		hasPrivate = hasPrivate || info.GetPrivate()
+
+		for _, workflow := range a.configuredWorkflows() {
+			if _, err := a.resolveWorkflow(ctx, repo, workflow); err != nil {
+				errs = append(errs, fmt.Errorf("repo %s/%s: %w", owner, name, err))
+			}
+		}
+	}
+
+	errs = append(errs, a.checkScopes(resp, hasPrivate)...)
+
+	return errors.Join(errs...)
+}
+
+// ValidatePeriod checks that the period is a real window of the past: EndDate is set, StartDate is before it
+// and neither is in the future. A swapped or empty period would otherwise have every metric silently return nothing.
+// Check, CheckRepos and CheckStream fail right away with its error.
+func (a *Analyzer) ValidatePeriod() error {
+	now := time.Now()
+	switch {
+	case a.EndDate.IsZero():
+		return errors.New("invalid period: EndDate is not set")
+	case !a.StartDate.Before(a.EndDate):
+		return fmt.Errorf("invalid period: StartDate %s must be before EndDate %s",
+			a.StartDate.Format("2006-01-02 15:04:05"), a.EndDate.Format("2006-01-02 15:04:05"))
+	case a.EndDate.After(now):
+		return fmt.Errorf("invalid period: EndDate %s is in the future", a.EndDate.Format("2006-01-02 15:04:05"))
+	}
+	return nil
+}
+
+// ErrMissingScope is returned by Validate when the token lacks an OAuth scope some metrics need.
+var ErrMissingScope = errors.New("token missing scope")
+
+// scopeMetrics lists, by classic OAuth scope, the metrics that fail without it. Without "repo" a token
+// only reads public repos, so the git data, mergeable state and Actions runs of private repos are out of reach.
+var scopeMetrics = map[string][]string{
+	"repo": {
+		"ConflictRateAndCount", "ResolvedConflictCount", "ChurnByFile", "LineChurnByFile", "CodeVolume", "MainSize",
+		"SuccessfulReruns", "WorkflowFailures", "SuccessfulDeploys", "WorkflowFailuresByName", "SuccessfulDeploysByName",
+		"WorkflowDurationHistogram", "DeploymentFrequency", "LeadTimeForChanges", "ChangeFailureRate",
+	},
+}
+
+// checkScopes compares the scopes granted to the token, from the X-OAuth-Scopes header of resp, with
+// scopeMetrics. The metrics of each missing scope are disabled in EnabledMetrics, unless explicitly enabled,
+// and an ErrMissingScope error is returned per scope. The "repo" scope is only required when a repo is private.
+// Fine-grained tokens and GitHub Apps don't send the header, so nothing is checked for them.
+func (a *Analyzer) checkScopes(resp *github.Response, hasPrivate bool) []error {
+	if resp == nil || resp.Header.Get("X-OAuth-Scopes") == "" {
+		return nil
+	}
+	granted := make(map[string]bool)
+	for _, scope := range strings.Split(resp.Header.Get("X-OAuth-Scopes"), ",") {
+		granted[strings.TrimSpace(scope)] = true
+	}
+
+	var errs []error
+	for scope, metrics := range scopeMetrics {
+		if granted[scope] || (scope == "repo" && !hasPrivate) {
+			continue
+		}
+		if a.EnabledMetrics == nil {
+			a.EnabledMetrics = make(map[string]bool)
+		}
+		for _, metric := range metrics {
+			if _, ok := a.EnabledMetrics[metric]; !ok {
+				a.EnabledMetrics[metric] = false
+			}
+		}
+		a.logEvent(slog.LevelWarn, "token missing scope, metrics disabled", "scope", scope, "metrics", metrics)
+		errs = append(errs, fmt.Errorf("%w %q; %s disabled", ErrMissingScope, scope, strings.Join(metrics, ", ")))
+	}
+	return errs
+}
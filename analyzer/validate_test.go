@@ -0,0 +1,24 @@
+package analyzer
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestValidateReposOnlyLooksUpTheGivenRepos(t *testing.T) {
+	// gone was deleted since the config was written
+	p := &fakeProvider{repos: map[string]*fakeRepo{"api": {}, "web": {}}}
+	a := newFakeAnalyzer(p, map[string][]string{"core": {"api", "gone"}, "front": {"web"}})
+	ctx := context.Background()
+
+	if err := a.ValidateRepos(ctx, []string{"api", "web"}); err != nil {
+		t.Errorf("ValidateRepos(api, web) = %v, want nil", err)
+	}
+	if got := p.callsTo("GetRepository"); got != 2 {
+		t.Errorf("ValidateRepos looked up %d repos, want 2", got)
+	}
+	if err := a.Validate(ctx); err == nil || !strings.Contains(err.Error(), "acme/gone") {
+		t.Errorf("Validate = %v, want an error for acme/gone", err)
+	}
+}
@@ -0,0 +1,39 @@
+package analyzer
+
+import (
+	"fmt"
+	"os"
+)
+
+// Formats lists the formats accepted by Write.
+var Formats = []string{"json", "ndjson", "csv", "markdown", "html"}
+
+// Write exports the metrics in the given format (one of Formats) and returns the path of the file.
+// An empty path is named after the period, and relative paths are placed under OutputDir (see outputPath).
+func (a *Analyzer) Write(metrics []RepoMetrics, format, path string) (string, error) {
+	switch format {
+	case "json":
+		return a.Export(metrics, path)
+	case "ndjson":
+		path, err := a.outputPath(path, ".ndjson")
+		if err != nil {
+			return "", err
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		if err := a.ExportNDJSON(metrics, f); err != nil {
+			return "", err
+		}
+		return path, f.Close()
+	case "csv":
+		return a.ExportCSV(metrics, path)
+	case "markdown":
+		return a.ExportMarkdown(metrics, path)
+	case "html":
+		return a.ExportHTML(metrics, path)
+	}
+	return "", fmt.Errorf("unknown format %q, valid formats: %v", format, Formats)
+}
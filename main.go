@@ -2,9 +2,12 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
 	"log/slog"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/raywall/using-gh-metrics/analyzer"
@@ -12,6 +15,19 @@ import (
 
 var svc *analyzer.Analyzer
 
+var (
+	discover  = flag.Bool("discover", false, "descobre os repositórios não arquivados da organização em vez de usar os configurados")
+	dryRun    = flag.Bool("dry-run", false, "valida a configuração (token, repositórios e workflow) sem coletar métricas")
+	outputDir = flag.String("output-dir", "", "diretório onde os relatórios são gravados (criado se não existir)")
+	repos     = flag.String("repos", "", "lista de repositórios separados por vírgula a analisar, em vez de todos os configurados")
+	resume    = flag.Bool("resume", false, "salva o progresso em "+analyzer.DefaultCheckpointFile+" e retoma uma execução interrompida a partir dele")
+	// no -token flag: a token on the command line shows up in ps and in the shell history
+	tokenFile = flag.String("token-file", "", "arquivo com o token do GitHub (sem ele, usa GITHUB_TOKEN ou o gh CLI)")
+	format    = flag.String("format", "json", "formato da exportação: "+strings.Join(analyzer.Formats, ", "))
+	merge     = flag.Bool("merge", false, "combina os relatórios JSON passados como argumentos (um por execução) em um só, sem consultar o GitHub")
+	details   = flag.Bool("details", false, "exporta também os PRs, commits e execuções de workflow usados nas métricas (arquivo details-*.json)")
+)
+
 func init() {
 	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
 		Level:     slog.LevelDebug,
@@ -21,13 +37,17 @@ func init() {
 	logger := slog.New(handler)
 	slog.SetDefault(logger)
 
-	svc = analyzer.NewAnalyzer(
+}
+
+// newAnalyzer creates the analyzer with the configuration of the run.
+func newAnalyzer(token string) *analyzer.Analyzer {
+	return analyzer.NewAnalyzer(
 		"raywall",
 		"main",
 		"2 - [DEV] Build & Deploy",
 		time.Now().AddDate(0, -13, 0).Truncate(24*time.Hour),
 		time.Now().AddDate(0, -1, 0).Truncate(24*time.Hour),
-		os.Getenv("GITHUB_TOKEN"),
+		token,
 		map[string][]string{
 			"Backend": {
 				"fast-service-toolkit",
@@ -47,10 +67,98 @@ func init() {
 }
 
 func main() {
+	flag.Parse()
 	ctx := context.Background()
-	metrics, err := svc.Check(ctx)
+
+	// checked up front, so a typo doesn't throw away a whole run
+	if !slices.Contains(analyzer.Formats, *format) {
+		log.Fatalf("formato %q inválido, use um de: %s", *format, strings.Join(analyzer.Formats, ", "))
+	}
+	if *resume && *repos != "" {
+		log.Fatal("-resume e -repos não podem ser usados juntos: a retomada sempre cobre todos os repositórios configurados")
+	}
+
+	if *merge {
+		if flag.NArg() == 0 {
+			log.Fatal("informe os relatórios a combinar, ex.: -merge backend.json frontend.json")
+		}
+		metrics, err := analyzer.MergeMetrics(flag.Args())
+		if err != nil {
+			log.Fatalf("falha ao combinar os relatórios: %v", err)
+		}
+		svc = newAnalyzer("")
+		svc.OutputDir = *outputDir
+		path, err := svc.Write(metrics, *format, "")
+		if err != nil {
+			log.Fatalf("falha ao exportar as métricas: %v", err)
+		}
+		log.Printf("%d repositórios combinados em %s", len(metrics), path)
+		return
+	}
+
+	ghToken, err := analyzer.ResolveToken(analyzer.TokenConfig{TokenFile: *tokenFile})
+	if err != nil {
+		log.Fatalf("falha ao obter o token do GitHub: %v", err)
+	}
+	svc = newAnalyzer(ghToken)
+	svc.OutputDir = *outputDir
+	svc.CollectDetails = *details
+	svc.Logger = analyzer.NewJSONLogger(os.Stdout)
+
+	if *discover {
+		projects, err := svc.DiscoverRepos(ctx, svc.Owner, analyzer.DiscoverOptions{})
+		if err != nil {
+			log.Fatalf("falha ao descobrir os repositórios de %s: %v", svc.Owner, err)
+		}
+		svc.Projects = projects
+	}
+
+	var names []string
+	for _, repo := range strings.Split(*repos, ",") {
+		if repo = strings.TrimSpace(repo); repo != "" {
+			names = append(names, repo)
+		}
+	}
+
+	// validated on every run, not only with -dry-run: it also disables the metrics the token's scopes can't
+	// serve, which would otherwise come out as silent zeros. With -repos only those repos are looked up.
+	validate := svc.Validate
+	if len(names) > 0 {
+		validate = func(ctx context.Context) error { return svc.ValidateRepos(ctx, names) }
+	}
+	if err := validate(ctx); err != nil {
+		if *dryRun {
+			log.Fatalf("configuração inválida:\n%v", err)
+		}
+		log.Printf("problemas na configuração, as métricas afetadas serão registradas como erro:\n%v", err)
+	}
+	if *dryRun {
+		log.Println("configuração válida")
+		return
+	}
+
+	var metrics []analyzer.RepoMetrics
+	if *resume {
+		metrics, err = svc.CheckResumable(ctx, analyzer.DefaultCheckpointFile)
+	} else if len(names) > 0 {
+		metrics, err = svc.CheckRepos(ctx, names)
+	} else {
+		metrics, err = svc.Check(ctx)
+	}
 	if err != nil {
 		log.Println("falha ao recuperar métricas do GitHub")
 	}
-	svc.Export(metrics, "output.json")
+	path, err := svc.Write(metrics, *format, "")
+	if err != nil {
+		log.Fatalf("falha ao exportar as métricas: %v", err)
+	}
+	log.Printf("métricas exportadas em %s", path)
+
+	if *details {
+		path, err := svc.ExportDetails(metrics, "")
+		if err != nil {
+			log.Fatalf("falha ao exportar os detalhes: %v", err)
+		}
+		log.Printf("detalhes exportados em %s", path)
+	}
 }